@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+)
+
+// maxBatchSize is the max number of items in a single batch request
+const maxBatchSize = 1000
+
+var errBatchBody = fmt.Errorf("Invalid batch. JSON array only")
+var errBatchSize = fmt.Errorf("Invalid batch. At most %d items", maxBatchSize)
+var errBatchItem = fmt.Errorf("Invalid item. JSON object of op, x and y only")
+var errMissOp = fmt.Errorf("op is not provided")
+
+// batchItem is an item of batch request.
+// op is name or action of the operation, like `sub` or `subtract`.
+// x and y can be JSON number or string
+type batchItem struct {
+	Op string          `json:"op"`
+	X  json.RawMessage `json:"x"`
+	Y  json.RawMessage `json:"y"`
+}
+
+// batchTask is a valid item waiting for result
+type batchTask struct {
+	index    int
+	op       ops.Operation
+	x, y     int64
+	cacheKey string
+	// ttl is noCache if cache is bypassed, see requestTTL
+	ttl time.Duration
+	// val and cached are filled by MGet
+	val    string
+	cached bool
+}
+
+// findOp return registered operation by name or action
+func findOp(s string) (ops.Operation, bool) {
+	if op, ok := ops.Get(s); ok {
+		return op, true
+	}
+	for _, op := range ops.All() {
+		if op.Action == s {
+			return op, true
+		}
+	}
+	return ops.Operation{}, false
+}
+
+var errUnsupportedOp = fmt.Errorf("Unsupported op")
+
+// errUnknownOp is returned if op of batch item is not registered
+func errUnknownOp(s string) error {
+	return fmt.Errorf("%w %q", errUnsupportedOp, s)
+}
+
+// batch endpoint, accept JSON array of {op, x, y} and
+// return results in the same order, with cached flag of each item.
+// invalid item get its own problem details, rest of the batch is not affected.
+// all valid items are looked up in cache with a single MGet.
+// Cache-Control is honored, same as calculation endpoints
+func batch(ctx *gin.Context) {
+	ttl, err := requestTTL(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	var items []json.RawMessage
+	err = ctx.ShouldBindJSON(&items)
+	if err != nil {
+		respondError(ctx, errBatchBody)
+		return
+	}
+	if len(items) > maxBatchSize {
+		respondError(ctx, errBatchSize)
+		return
+	}
+	Debug.Println("recieved batch of", len(items))
+	respond(ctx, 200, getBatchResults(items, ttl, ctx.GetString(requestIDKey)))
+}
+
+// getBatchResults validate items, look up all keys in one round trip,
+// then calculate and cache the missing ones.
+// ttl return TTL of each op, items of noCache are not looked up.
+// requestID is returned in problem details of invalid items
+func getBatchResults(items []json.RawMessage, ttl func(name string) time.Duration, requestID string) []gin.H {
+	results := make([]gin.H, len(items))
+	tasks := make([]*batchTask, 0, len(items))
+	for i, item := range items {
+		task, err := batchValidation(item)
+		if err != nil {
+			results[i] = problemOf(err, codeInternal).body("", requestID)
+			continue
+		}
+		task.index, task.ttl = i, ttl(task.op.Name)
+		tasks = append(tasks, task)
+	}
+
+	lookups := make([]*batchTask, 0, len(tasks))
+	keys := make([]string, 0, len(tasks))
+	ttls := make([]time.Duration, 0, len(tasks))
+	for _, task := range tasks {
+		if task.ttl != noCache {
+			lookups = append(lookups, task)
+			keys, ttls = append(keys, task.cacheKey), append(ttls, task.ttl)
+		}
+	}
+	if len(lookups) > 0 {
+		values, oks := cache.MGet(keys, ttls)
+		for i, task := range lookups {
+			task.val, task.cached = values[i], oks[i]
+		}
+	}
+	for _, task := range tasks {
+		results[task.index] = batchResult(task, requestID)
+	}
+	return results
+}
+
+// batchValidation decode item and checks op, x and y.
+// task with cache key is returned if item is valid
+func batchValidation(raw json.RawMessage) (*batchTask, error) {
+	var item batchItem
+	err := json.Unmarshal(raw, &item)
+	if err != nil {
+		return nil, errBatchItem
+	}
+	if item.Op == "" {
+		return nil, &fieldError{field: "op", err: errMissOp}
+	}
+	op, ok := findOp(item.Op)
+	if ok == false {
+		return nil, &fieldError{field: "op", value: item.Op, err: errUnknownOp(item.Op)}
+	}
+	// item has x and y only
+	if op.Arity != 2 {
+		return nil, &fieldError{field: "op", value: item.Op, err: errOperandCount(op)}
+	}
+	x, y := rawOperand(item.X), rawOperand(item.Y)
+	err = qsValidation(x, y)
+	if err != nil {
+		return nil, err
+	}
+	values, err := opValidation(op, []string{x, y})
+	if err != nil {
+		return nil, namedOperand(err, false)
+	}
+	return &batchTask{op: op, x: values[0], y: values[1], cacheKey: genCacheKey(op.Name, values...)}, nil
+}
+
+// rawOperand return operand as string, so that it is validated the same way as query string.
+// JSON string is unquoted, number is kept as is, missing or null is empty string
+func rawOperand(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// batchResult is the getResult of batch item,
+// cache has been looked up already by getBatchResults
+func batchResult(task *batchTask, requestID string) gin.H {
+	op := task.op
+	start := time.Now()
+	values := []int64{task.x, task.y}
+	e := newCalcEvent(op.Name, typeInteger, len(values), func(i int) interface{} { return values[i] })
+	if task.cached {
+		result, err := stringToInt(task.val)
+		if err == nil {
+			cache.IncrCounter()
+			publishCalc(e, start, task.val, true, nil)
+			return gin.H{"action": op.Action, "x": task.x, "y": task.y, "answer": result, "cached": true}
+		}
+		Warning.Printf("invalid cached value %v of key %v\n", task.val, task.cacheKey)
+	}
+	result, err := calculate(op.Name, task.x, task.y)
+	publishCalc(e, start, strconv.FormatInt(result, 10), false, err)
+	if err != nil {
+		return problemOf(err, codeCalculation).body("", requestID)
+	}
+	if task.ttl != noCache {
+		cache.SetWithTTL(task.cacheKey, strconv.FormatInt(result, 10), task.ttl)
+	}
+	return gin.H{"action": op.Action, "x": task.x, "y": task.y, "answer": result, "cached": false}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const (
+	precisionInt = "int"
+	precisionBig = "big"
+)
+
+var errPrecision = fmt.Errorf("Unsupported precision. %v or %v only", precisionInt, precisionBig)
+
+// defaultPrecision will be used if precision is not set in query string.
+// can be changed via --precision flag
+var defaultPrecision = precisionInt
+
+// validPrecision checks if p is one of the supported precision
+func validPrecision(p string) bool {
+	return p == precisionInt || p == precisionBig
+}
+
+// genBigCacheKey generate key of cache in format of `big:func:v1:v2:...`
+// same as genCacheKey, values will be sorted for commutative operation.
+// prefix `big:` keeps big results apart from int64 results.
+// division shares cache with /divide of defaultDivMode, see genBigDivCacheKey
+func genBigCacheKey(f string, values ...*big.Int) string {
+	if f == "div" {
+		return genBigDivCacheKey(defaultDivMode, values...)
+	}
+	if getOp(f).Commutative {
+		values = append([]*big.Int(nil), values...)
+		sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	}
+	keys := make([]interface{}, len(values))
+	for i, v := range values {
+		keys[i] = v
+	}
+	return genUnSortedCacheKey(precisionBig+":"+f, keys...)
+}
+
+// calculateBig do the calculation with Big of the registered operation.
+// caller should make sure Big is not nil.
+// division is in defaultDivMode, same as its cache key
+func calculateBig(f string, values ...*big.Int) (*big.Int, error) {
+	if f == "div" {
+		return bigDivideWithMode(defaultDivMode, values...), nil
+	}
+	return getOp(f).Big(values)
+}
+
+// getBigResult is the big.Int version of getResult.
+// value is cached as decimal string
+func getBigResult(ttl time.Duration, f string, values ...*big.Int) (*big.Int, bool, error) {
+	cacheKey := genBigCacheKey(f, values...)
+	e := newCalcEvent(f, precisionBig, len(values), func(i int) interface{} { return values[i] })
+	val, cached, err := cachedResult(cacheKey, ttl, e, func() (string, error) {
+		result, err := calculateBig(f, values...)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	result, ok := new(big.Int).SetString(val, 10)
+	if ok == false {
+		Warning.Printf("invalid cached value %v of key %v\n", val, cacheKey)
+		result, err = calculateBig(f, values...)
+		return result, false, err
+	}
+	return result, cached, nil
+}
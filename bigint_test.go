@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func mustBig(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if ok == false {
+		panic("invalid big int " + s)
+	}
+	return v
+}
+
+func TestGenBigCacheKey(t *testing.T) {
+	cases := []struct {
+		name, f, v1, v2, expected string
+	}{
+		{name: "case 1", f: "add", v1: "4", v2: "0", expected: "big:add:0:4"},
+		{name: "case 2", f: "add", v1: "0", v2: "4", expected: "big:add:0:4"},
+		{name: "case 3", f: "div", v1: "4", v2: "1", expected: "big:div:floor:4:1"},
+		{name: "case 4", f: "mul", v1: "99999999999999999999", v2: "-1", expected: "big:mul:-1:99999999999999999999"},
+		{name: "case 5", f: "sub", v1: "99999999999999999999", v2: "-1", expected: "big:sub:99999999999999999999:-1"},
+	}
+	for _, c := range cases {
+		got := genBigCacheKey(c.f, mustBig(c.v1), mustBig(c.v2))
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestCalculateBig(t *testing.T) {
+	cases := []struct {
+		name, f, v1, v2, expected string
+	}{
+		{name: "case add", f: "add", v1: "9223372036854775807", v2: "1", expected: "9223372036854775808"},
+		{name: "case sub", f: "sub", v1: "-9223372036854775808", v2: "1", expected: "-9223372036854775809"},
+		{name: "case mul", f: "mul", v1: "9223372036854775807", v2: "9223372036854775807", expected: "85070591730234615847396907784232501249"},
+		{name: "case div", f: "div", v1: "-9223372036854775808", v2: "-1", expected: "9223372036854775808"},
+		{name: "case div floor", f: "div", v1: "-7", v2: "2", expected: "-4"},
+		{name: "case mod", f: "mod", v1: "-7", v2: "3", expected: "2"},
+		{name: "case rem", f: "rem", v1: "-7", v2: "3", expected: "-1"},
+	}
+	for _, c := range cases {
+		got, _ := calculateBig(c.f, mustBig(c.v1), mustBig(c.v2))
+		if got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestGetBigResult(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, f, x, y, expected string
+		expBool                 bool
+		fCache                  *fakeCacheClient
+	}{
+		{
+			name: "case 1", f: "add", x: "18446744073709551615", y: "1", expected: "18446744073709551616",
+			fCache: NewFakeCache(), expBool: false,
+		},
+		{
+			name: "case 2", f: "add", x: "18446744073709551615", y: "1", expected: "18446744073709551616",
+			fCache: &fakeCacheClient{val: map[string]string{"big:add:1:18446744073709551615": "18446744073709551616"}}, expBool: true,
+		},
+		{
+			name: "case 3", f: "add", x: "1", y: "1", expected: "2",
+			fCache: &fakeCacheClient{val: map[string]string{"big:add:1:1": "foo"}}, expBool: false,
+		},
+	}
+	for _, c := range cases {
+		cache = c.fCache
+
+		got, gotBool, _ := getBigResult(time.Minute, c.f, mustBig(c.x), mustBig(c.y))
+		if got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+		if gotBool != c.expBool {
+			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"strings"
+)
+
+var errBody = fmt.Errorf("Invalid body. JSON, XML, msgpack or form with x and y (or values) only")
+
+// operand is x, y or one of values in request body.
+// it is kept as string so that it is validated the same way as query string,
+// number is accepted as well as string in JSON and msgpack body
+type operand string
+
+// UnmarshalJSON accept both JSON number and string
+func (o *operand) UnmarshalJSON(b []byte) error {
+	*o = operand(rawOperand(b))
+	return nil
+}
+
+// CodecEncodeSelf encode operand as msgpack string
+func (o *operand) CodecEncodeSelf(e *codec.Encoder) {
+	e.MustEncode(string(*o))
+}
+
+// CodecDecodeSelf accept msgpack integer, float and string.
+// value is converted to string, validation is left to opValidation
+func (o *operand) CodecDecodeSelf(d *codec.Decoder) {
+	var v interface{}
+	d.MustDecode(&v)
+	switch v := v.(type) {
+	case []byte:
+		*o = operand(v)
+	default:
+		*o = operand(fmt.Sprint(v))
+	}
+}
+
+// operandsBody is the request body of POST endpoints, like
+// `{"x": 1, "y": 2}`, `<body><x>1</x><y>2</y></body>` or `x=1&y=2`.
+// list of operands is `values`, form accepts repeated v and comma separated values
+// same as query string
+type operandsBody struct {
+	X      operand   `form:"x" json:"x" xml:"x"`
+	Y      operand   `form:"y" json:"y" xml:"y"`
+	V      []operand `form:"v" json:"-" xml:"-"`
+	Values []operand `form:"values" json:"values" xml:"values"`
+}
+
+// bodyOperands is the request body version of queryOperands.
+// binding is chosen by Content-Type, form is the default
+func bodyOperands(ctx *gin.Context) (string, string, []string, error) {
+	b := binding.Default(ctx.Request.Method, ctx.ContentType())
+	if b == binding.ProtoBuf {
+		return "", "", nil, errBody
+	}
+	var body operandsBody
+	err := ctx.ShouldBindWith(&body, b)
+	if err != nil {
+		Debug.Println("bind body err:", err)
+		return "", "", nil, errBody
+	}
+	values := make([]string, 0, len(body.V)+len(body.Values))
+	for _, v := range body.V {
+		values = append(values, string(v))
+	}
+	for _, v := range body.Values {
+		if b == binding.Form {
+			values = append(values, strings.Split(string(v), ",")...)
+			continue
+		}
+		values = append(values, string(v))
+	}
+	return string(body.X), string(body.Y), values, nil
+}
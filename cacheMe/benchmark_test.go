@@ -0,0 +1,150 @@
+package cacheMe
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// benchKeys is the key space of benchmarks, large enough that a full scan of the map is visible
+const benchKeys = 1000000
+
+// benchTTL is TTL of benchmarks, keys are set to expire evenly within it,
+// so that there are always keys to expire while benchmark runs
+const benchTTL = 10 * time.Second
+
+// benchClient is the part of cache called by benchmarks
+type benchClient interface {
+	Get(key string, ttl time.Duration) (string, bool)
+	SetWithTTL(key string, value string, ttl time.Duration)
+	Close()
+}
+
+// scanCache is the baseline of benchmarks: a single lock guards one map,
+// and every sweep interval the whole map is scanned for expired kv with the lock held
+type scanCache struct {
+	mutex sync.Mutex
+	val   map[string]*valueStruct
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newScanCache(sweepInterval time.Duration) *scanCache {
+	c := &scanCache{val: make(map[string]*valueStruct), done: make(chan struct{})}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		tickCh := time.NewTicker(sweepInterval)
+		defer tickCh.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-tickCh.C:
+				c.mutex.Lock()
+				now := time.Now().UnixNano()
+				for key, val := range c.val {
+					if isExpired(val.expTS, now) {
+						delete(c.val, key)
+					}
+				}
+				c.mutex.Unlock()
+			}
+		}
+	}()
+	return c
+}
+
+// Get renew TTL on every hit, same as sliding policy
+func (c *scanCache) Get(key string, ttl time.Duration) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := time.Now().UnixNano()
+	val, ok := c.val[key]
+	if ok == false || isExpired(val.expTS, now) {
+		return "", false
+	}
+	val.expTS = expiration(now, ttl)
+	return val.value, true
+}
+
+func (c *scanCache) SetWithTTL(key string, value string, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := time.Now().UnixNano()
+	c.val[key] = &valueStruct{key: key, value: value, expTS: expiration(now, ttl), setTS: now, index: -1}
+}
+
+func (c *scanCache) Close() {
+	close(c.done)
+	c.wg.Wait()
+}
+
+// benchCache return c with all keys of benchmarks set
+func benchCache(c benchClient) (benchClient, []string) {
+	r := rand.New(rand.NewSource(1))
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "add:" + strconv.Itoa(i) + ":1"
+		c.SetWithTTL(keys[i], strconv.Itoa(i+1), time.Duration(r.Int63n(int64(benchTTL)))+1)
+	}
+	return c, keys
+}
+
+// benchmarkLatency calls Get and SetWithTTL of c in parallel, 1 of 10 is SetWithTTL.
+// p50, p99, p99.9 and max latency of a single call are reported
+func benchmarkLatency(b *testing.B, c benchClient, keys []string) {
+	var mutex sync.Mutex
+	var latencies []time.Duration
+	var seed int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		local := make([]time.Duration, 0, 1<<16)
+		for pb.Next() {
+			key := keys[r.Intn(len(keys))]
+			start := time.Now()
+			if r.Intn(10) == 0 {
+				c.SetWithTTL(key, "1", benchTTL)
+			} else {
+				c.Get(key, benchTTL)
+			}
+			local = append(local, time.Since(start))
+		}
+		mutex.Lock()
+		latencies = append(latencies, local...)
+		mutex.Unlock()
+	})
+	b.StopTimer()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)*50/100]), "p50-ns")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100]), "p99-ns")
+	b.ReportMetric(float64(latencies[len(latencies)*999/1000]), "p99.9-ns")
+	b.ReportMetric(float64(latencies[len(latencies)-1]), "max-ns")
+}
+
+// BenchmarkDefaultCache compares scanCache with a single lock and with shards,
+// sweep interval is 1 second so that sweeps happen while benchmark runs
+func BenchmarkDefaultCache(b *testing.B) {
+	cases := []struct {
+		name     string
+		newCache func() benchClient
+	}{
+		{name: "scan", newCache: func() benchClient { return newScanCache(time.Second) }},
+		{name: "shards=1", newCache: func() benchClient { return NewDefaultClient(Policy{}, Shards(1), SweepInterval(time.Second)) }},
+		{name: "shards=" + strconv.Itoa(DefaultShards), newCache: func() benchClient {
+			return NewDefaultClient(Policy{}, Shards(DefaultShards), SweepInterval(time.Second))
+		}},
+	}
+	for _, c := range cases {
+		cache, keys := benchCache(c.newCache())
+		b.Run(c.name, func(b *testing.B) {
+			benchmarkLatency(b, cache, keys)
+		})
+		cache.Close()
+	}
+}
@@ -0,0 +1,157 @@
+package cacheMe
+
+import (
+	"github.com/alicebob/miniredis"
+	"testing"
+	"time"
+)
+
+// expirer is the part of cache under conformance test
+type expirer interface {
+	Get(key string, ttl time.Duration) (string, bool)
+	SetWithTTL(key string, value string, ttl time.Duration)
+}
+
+// backend is a cache of policy, advance moves its clock forward
+type backend struct {
+	name    string
+	cache   expirer
+	advance func(d time.Duration)
+}
+
+// newBackends return DefaultCache and RedisClient of policy, stop is called once test is done
+func newBackends(t *testing.T, policy Policy) ([]backend, func()) {
+	now := testNow
+	dc := newDefaultCache(policy)
+	dc.clock = func() time.Time { return now }
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	redisC := NewRedisClient("redis://"+s.Addr(), policy)
+	backends := []backend{
+		{name: "default", cache: dc, advance: func(d time.Duration) { now = now.Add(d) }},
+		{name: "redis", cache: redisC, advance: s.FastForward},
+	}
+	return backends, func() {
+		redisC.Close()
+		s.Close()
+	}
+}
+
+// TestExpirationConformance runs the same steps against both backends.
+// each step waits first, then sets foo with ttl, or gets foo with ttl and checks if it is a hit
+func TestExpirationConformance(t *testing.T) {
+	type step struct {
+		wait   time.Duration
+		set    bool
+		ttl    time.Duration
+		expHit bool
+	}
+	sliding, absolute := Policy{Expiration: Sliding}, Policy{Expiration: Absolute}
+	capped := Policy{Expiration: Sliding, MaxLifetime: 15 * time.Second}
+	cases := []struct {
+		name   string
+		policy Policy
+		steps  []step
+	}{
+		{name: "sliding", policy: sliding, steps: []step{
+			{set: true, ttl: 10 * time.Second},
+			{wait: 6 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: 6 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: 10 * time.Second, ttl: 10 * time.Second, expHit: false},
+		}},
+		{name: "sliding is zero value", policy: Policy{}, steps: []step{
+			{set: true, ttl: 10 * time.Second},
+			{wait: 9 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: 9 * time.Second, ttl: 10 * time.Second, expHit: true},
+		}},
+		{name: "sliding renew with new ttl", policy: sliding, steps: []step{
+			{set: true, ttl: 10 * time.Second},
+			{wait: 5 * time.Second, ttl: time.Hour, expHit: true},
+			{wait: 30 * time.Minute, ttl: 0, expHit: true},
+			{wait: 1000 * time.Hour, ttl: 0, expHit: true},
+		}},
+		{name: "absolute", policy: absolute, steps: []step{
+			{set: true, ttl: 10 * time.Second},
+			{wait: 6 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: 3 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: time.Second, ttl: 10 * time.Second, expHit: false},
+		}},
+		{name: "absolute set again", policy: absolute, steps: []step{
+			{set: true, ttl: 10 * time.Second},
+			{wait: 8 * time.Second, set: true, ttl: 10 * time.Second},
+			{wait: 8 * time.Second, ttl: 10 * time.Second, expHit: true},
+		}},
+		{name: "no expiry", policy: absolute, steps: []step{
+			{set: true, ttl: 0},
+			{wait: 1000 * time.Hour, ttl: 0, expHit: true},
+		}},
+		{name: "sliding with max lifetime", policy: capped, steps: []step{
+			{set: true, ttl: 10 * time.Second},
+			{wait: 6 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: 6 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: 3 * time.Second, ttl: 10 * time.Second, expHit: false},
+		}},
+		{name: "max lifetime of ttl shorter than lifetime", policy: capped, steps: []step{
+			{set: true, ttl: 5 * time.Second},
+			{wait: 4 * time.Second, ttl: 5 * time.Second, expHit: true},
+			{wait: 5 * time.Second, ttl: 5 * time.Second, expHit: false},
+		}},
+		{name: "max lifetime of no expiry", policy: capped, steps: []step{
+			{set: true, ttl: 0},
+			{wait: 14 * time.Second, ttl: 0, expHit: true},
+			{wait: time.Second, ttl: 0, expHit: false},
+		}},
+		{name: "max lifetime reset by set", policy: capped, steps: []step{
+			{set: true, ttl: 10 * time.Second},
+			{wait: 8 * time.Second, set: true, ttl: 10 * time.Second},
+			{wait: 8 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: 6 * time.Second, ttl: 10 * time.Second, expHit: true},
+			{wait: time.Second, ttl: 10 * time.Second, expHit: false},
+		}},
+		{name: "absolute with max lifetime", policy: Policy{Expiration: Absolute, MaxLifetime: time.Minute}, steps: []step{
+			{set: true, ttl: time.Hour},
+			{wait: 59 * time.Second, ttl: time.Hour, expHit: true},
+			{wait: time.Second, ttl: time.Hour, expHit: false},
+		}},
+	}
+
+	for _, c := range cases {
+		backends, stop := newBackends(t, c.policy)
+		for _, b := range backends {
+			for i, s := range c.steps {
+				b.advance(s.wait)
+				if s.set {
+					b.cache.SetWithTTL("foo", "1", s.ttl)
+					continue
+				}
+				_, gotHit := b.cache.Get("foo", s.ttl)
+				if gotHit != s.expHit {
+					t.Errorf("error on: %v of %v, step %v\ngot hit:\n %v \nexp hit\n %v \n", c.name, b.name, i, gotHit, s.expHit)
+				}
+			}
+		}
+		stop()
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	cases := []struct {
+		name, expiration string
+		maxLifetime      time.Duration
+		exp              Policy
+		expErr           error
+	}{
+		{name: "case default", expiration: "", exp: Policy{}},
+		{name: "case absolute", expiration: Absolute, maxLifetime: time.Hour, exp: Policy{Expiration: Absolute, MaxLifetime: time.Hour}},
+		{name: "case unknown", expiration: "lru", expErr: ErrExpiration},
+		{name: "case negative", expiration: Sliding, maxLifetime: -time.Second, expErr: ErrMaxLifetime},
+	}
+	for _, c := range cases {
+		got, gotErr := NewPolicy(c.expiration, c.maxLifetime)
+		if got != c.exp || gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot policy and err:\n %v %v \nexp policy and err\n %v %v \n", c.name, got, gotErr, c.exp, c.expErr)
+		}
+	}
+}
@@ -1,114 +1,462 @@
 package cacheMe
 
 import (
+	"container/heap"
+	"container/list"
+	"runtime"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// entryOverhead is the approximate bytes of a kv besides key and value,
+// like map bucket, valueStruct and element of LRU list
+const entryOverhead = 128
+
+// sweepBatch is the max number of kv visited by cronJob while holding lock of a shard,
+// so that requests are not blocked long when many keys expire at once
+const sweepBatch = 256
+
+// DefaultShards and DefaultSweepInterval are used unless set by Shards and SweepInterval
+const (
+	DefaultShards        = 32
+	DefaultSweepInterval = 5 * time.Second
+)
+
 type valueStruct struct {
-	value int
+	key   string
+	value string
 	expTS int64
+	setTS int64
+	// elem is the element of kv in LRU list of its shard, nil if cache is unbounded
+	elem *list.Element
+	// usedSeq is the bound sequence number kv was last set or hit at, see bound
+	usedSeq uint64
+	// heapTS is the expiration ts kv is ordered by in expiry heap, no later than expTS.
+	// renewing kv to a later expTS leaves heapTS as is, kv is moved once heapTS is due, see sweep
+	heapTS int64
+	// index is the index of kv in expiry heap, -1 if kv is not in it
+	index int
+}
+
+// Option configures DefaultCache, see NewDefaultClient
+type Option func(c *DefaultCache)
+
+// MaxEntries limits number of keys of all shards, 0 is no limit
+func MaxEntries(n int) Option {
+	return func(c *DefaultCache) { c.maxEntries = n }
+}
+
+// MaxBytes limits approximate bytes of keys and values of all shards, see entrySize. 0 is no limit
+func MaxBytes(n int64) Option {
+	return func(c *DefaultCache) { c.maxBytes = n }
+}
+
+// Shards set number of shards, DefaultShards if n is less than 1
+func Shards(n int) Option {
+	return func(c *DefaultCache) { c.shardCount = n }
+}
+
+// SweepInterval set how often expired keys are removed in background, DefaultSweepInterval if d is not positive
+func SweepInterval(d time.Duration) Option {
+	return func(c *DefaultCache) { c.sweepInterval = d }
 }
 
 // DefaultCache will use local memory.
-// All kv will be stored in a map
+// keys are spread over shards by hash, each shard has its own lock,
+// so that requests of different shards never wait for each other.
+// in each shard, all kv will be stored in a map
 // key of the map is the key value
 // value is pointer to struct valueStruct which store the value and expiration info
-// expiration ts will be the number of seconds elapsed since January 1, 1970 UTC
+// expiration ts will be the number of nanoseconds elapsed since January 1, 1970 UTC,
+// 0 if kv never expires. set ts is when kv was set, lifetime is capped from it, see Policy
 // kv can expired (deleted) in 2 ways
 // 1. when accessing the cache via Get method, delete the kv if expired
-// 2. there will be a goroutine running in background and scan the map in every 5 seconds
+// 2. there will be a goroutine running in background every sweep interval,
+// it pops due kv from expiry heap of each shard, kv not due yet are never visited
+// if cache is bounded by MaxEntries or MaxBytes, least recently used kv of all shards is evicted once bound is exceeded
+// all methods are safe for concurrent use, kv are guarded by lock of their shard and counters are atomic
 type DefaultCache struct {
-	mutex *sync.Mutex
-	val   map[string]*valueStruct
-	done  chan struct{}
-	hit   int
+	// hit is accessed atomically, first field so that it is 64-bit aligned
+	hit    int64
+	shards []*shard
+	done   chan struct{}
+	// closeOnce closes done once, wg waits for cronJob to exit
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+	policy    Policy
+	// clock is time.Now, replaced in tests
+	clock func() time.Time
+	// bound is shared by all shards, nil if cache is unbounded
+	bound *bound
+
+	maxEntries    int
+	maxBytes      int64
+	shardCount    int
+	sweepInterval time.Duration
+}
+
+// shard is a part of DefaultCache, see shardOf
+type shard struct {
+	// evicted and expired are number of kv removed by bound and by TTL,
+	// accessed atomically, first fields so that they are 64-bit aligned
+	evicted, expired int64
+	mutex            sync.Mutex
+	val              map[string]*valueStruct
+	// expiry is a min-heap of kv by heapTS, kv never expires is not in it once its heapTS is due
+	expiry expiryHeap
+	// lru has kv from the most to the least recently used, nil if cache is unbounded
+	lru   *list.List
+	bound *bound
+	// bytes is the approximate bytes of all kv
+	bytes int64
+}
+
+// bound is MaxEntries and MaxBytes of all shards.
+// each shard has its own LRU list, kv are stamped with seq when set or hit,
+// so that the least recently used kv of all shards is the back of a list with the smallest usedSeq
+type bound struct {
+	// entries and bytes are totals of all shards, seq is the last usedSeq,
+	// accessed atomically, first fields so that they are 64-bit aligned
+	entries, bytes int64
+	seq            uint64
+	// mutex is held while evicting, so that kv are evicted by one goroutine at a time
+	mutex      sync.Mutex
+	maxEntries int
+	maxBytes   int64
 }
 
-// NewDefaultClient return a new defaultCache
+// NewDefaultClient return a new defaultCache, keys expire by policy,
+// opts set bound, shards and sweep interval of the cache, unbounded if there is no bound
 // Also create a goroutine that periodically expire keys
-func NewDefaultClient() *DefaultCache {
-	v := make(map[string]*valueStruct)
+func NewDefaultClient(policy Policy, opts ...Option) *DefaultCache {
+	c := newDefaultCache(policy, opts...)
+	c.wg.Add(1)
+	go c.cronJob()
+	return c
+}
+
+// newDefaultCache return DefaultCache of NewDefaultClient without starting cronJob
+func newDefaultCache(policy Policy, opts ...Option) *DefaultCache {
 	done := make(chan struct{})
-	mutex := &sync.Mutex{}
-	// init cahce, also, start a goroutine, periodically check and expire cache data
 	c := &DefaultCache{
-		mutex: mutex,
-		val:   v,
-		done:  done,
+		done:   done,
+		policy: policy,
+		clock:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.shardCount < 1 {
+		c.shardCount = DefaultShards
+	}
+	if c.sweepInterval <= 0 {
+		c.sweepInterval = DefaultSweepInterval
+	}
+	if c.maxEntries > 0 || c.maxBytes > 0 {
+		c.bound = &bound{maxEntries: c.maxEntries, maxBytes: c.maxBytes}
+	}
+	c.shards = make([]*shard, c.shardCount)
+	for i := range c.shards {
+		c.shards[i] = newShard(c.bound)
 	}
-	go c.cronJob()
 	return c
 }
 
-// Get will get value and extend TTL if exist.
-// If not, return 0 and false
-func (c *DefaultCache) Get(key string) (int, bool) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	val, ok := c.val[key]
+// newShard return empty shard, LRU list is created only if it is bounded
+func newShard(b *bound) *shard {
+	s := &shard{val: make(map[string]*valueStruct), bound: b}
+	if b != nil {
+		s.lru = list.New()
+	}
+	return s
+}
+
+// shardOf return shard of key by FNV-1a hash
+func (c *DefaultCache) shardOf(key string) *shard {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return c.shards[h%uint32(len(c.shards))]
+}
+
+// Get will get value and renew TTL to ttl if exist and policy is sliding.
+// If not, return empty string and false
+func (c *DefaultCache) Get(key string, ttl time.Duration) (string, bool) {
+	s := c.shardOf(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.get(key, ttl, c.clock().UnixNano(), c.policy)
+}
+
+// MGet is the batch version of Get, lock of each shard is held once per key.
+// TTL of keys[i] is renewed to ttls[i], values and bools are in the same order of keys
+func (c *DefaultCache) MGet(keys []string, ttls []time.Duration) ([]string, []bool) {
+	values := make([]string, len(keys))
+	oks := make([]bool, len(keys))
+	now := c.clock().UnixNano()
+	for i, key := range keys {
+		s := c.shardOf(key)
+		s.mutex.Lock()
+		values[i], oks[i] = s.get(key, ttls[i], now, c.policy)
+		s.mutex.Unlock()
+	}
+	return values, oks
+}
+
+// get is Get with lock held at now
+func (s *shard) get(key string, ttl time.Duration, now int64, policy Policy) (string, bool) {
+	val, ok := s.val[key]
 	if ok == false {
-		return 0, false
+		return "", false
+	}
+	if isExpired(val.expTS, now) {
+		s.remove(val)
+		atomic.AddInt64(&s.expired, 1)
+		return "", false
+	}
+	if val.elem != nil {
+		s.use(val)
+	}
+	if policy.sliding() {
+		remaining := time.Duration(val.setTS + int64(policy.MaxLifetime) - now)
+		s.setExpiration(val, expiration(now, policy.capped(ttl, remaining)))
+	}
+	return val.value, true
+}
+
+// SetWithTTL will set the key value, and set expiration to ttl from now.
+// 0 ttl is no expiry.
+// least recently used kv are evicted once lock of the shard is released,
+// size of cache may exceed bound only while other keys are being set
+func (c *DefaultCache) SetWithTTL(key string, value string, ttl time.Duration) {
+	s := c.shardOf(key)
+	s.mutex.Lock()
+	now := c.clock().UnixNano()
+	val, ok := s.val[key]
+	if ok {
+		s.account(0, -entrySize(key, val.value))
+		val.value, val.setTS = value, now
+		if val.elem != nil {
+			s.use(val)
+		}
+	} else {
+		val = &valueStruct{key: key, value: value, setTS: now, index: -1}
+		s.val[key] = val
+		s.account(1, 0)
+		if s.lru != nil {
+			val.elem = s.lru.PushFront(val)
+			s.use(val)
+		}
+	}
+	s.account(0, entrySize(key, value))
+	s.setExpiration(val, expiration(now, c.policy.capped(ttl, c.policy.MaxLifetime)))
+	s.mutex.Unlock()
+	if c.bound != nil {
+		c.bound.evict(c.shards)
 	}
-	if isExpired(val.expTS) {
-		delete(c.val, key)
-		return 0, false
+}
+
+// use move val to the front of LRU list and stamp it with the next seq of bound, with lock held
+func (s *shard) use(val *valueStruct) {
+	s.lru.MoveToFront(val.elem)
+	val.usedSeq = atomic.AddUint64(&s.bound.seq, 1)
+}
+
+// account add n kv and bytes to the shard, and to totals of bound if cache is bounded, with lock held
+func (s *shard) account(n, bytes int64) {
+	s.bytes += bytes
+	if s.bound != nil {
+		atomic.AddInt64(&s.bound.entries, n)
+		atomic.AddInt64(&s.bound.bytes, bytes)
 	}
+}
 
-	val.expTS += 60
-	return val.value, ok
+// setExpiration set expTS of val, heap is updated only if val expires earlier than its heapTS,
+// so that renewing on every hit with sliding policy is O(1)
+func (s *shard) setExpiration(val *valueStruct, expTS int64) {
+	val.expTS = expTS
+	switch {
+	case expTS == 0:
+		// kv stays in heap if it is, and leaves once its heapTS is due
+	case val.index < 0:
+		val.heapTS = expTS
+		heap.Push(&s.expiry, val)
+	case expTS < val.heapTS:
+		val.heapTS = expTS
+		heap.Fix(&s.expiry, val.index)
+	}
 }
 
-// SetWithTTL will set the key value, and set expiration to 60 second
-func (c *DefaultCache) SetWithTTL(key string, value int) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	exp := time.Now().Unix() + 60
-	c.val[key] = &valueStruct{value: value, expTS: exp}
+// exceeded checks totals of all shards against bound
+func (b *bound) exceeded() bool {
+	return (b.maxEntries > 0 && atomic.LoadInt64(&b.entries) > int64(b.maxEntries)) ||
+		(b.maxBytes > 0 && atomic.LoadInt64(&b.bytes) > b.maxBytes)
+}
+
+// evict remove the least recently used kv of all shards until cache is within bound.
+// lock of shards must not be held, each of them is locked in turn to find the back of its LRU list with the smallest usedSeq.
+// kv larger than MaxBytes is evicted at once
+func (b *bound) evict(shards []*shard) {
+	if b.exceeded() == false {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for b.exceeded() {
+		var oldest *shard
+		var seq uint64
+		for _, s := range shards {
+			s.mutex.Lock()
+			if back := s.lru.Back(); back != nil && (oldest == nil || back.Value.(*valueStruct).usedSeq < seq) {
+				oldest, seq = s, back.Value.(*valueStruct).usedSeq
+			}
+			s.mutex.Unlock()
+		}
+		if oldest == nil {
+			return
+		}
+		// back of oldest is compared again, it is hit or removed meanwhile if usedSeq differs
+		oldest.mutex.Lock()
+		if back := oldest.lru.Back(); back != nil && back.Value.(*valueStruct).usedSeq == seq {
+			oldest.remove(back.Value.(*valueStruct))
+			atomic.AddInt64(&oldest.evicted, 1)
+		}
+		oldest.mutex.Unlock()
+	}
+}
+
+// sweep visit at most limit kv of due heapTS at now, return number of them. 0 limit is no limit.
+// kv expired is removed, kv renewed since is moved to its expTS, kv never expires leaves heap
+func (s *shard) sweep(now int64, limit int) int {
+	n := 0
+	for len(s.expiry) > 0 && s.expiry[0].heapTS <= now && (limit == 0 || n < limit) {
+		val := s.expiry[0]
+		switch {
+		case isExpired(val.expTS, now):
+			s.remove(val)
+			atomic.AddInt64(&s.expired, 1)
+		case val.expTS == 0:
+			heap.Pop(&s.expiry)
+		default:
+			val.heapTS = val.expTS
+			heap.Fix(&s.expiry, 0)
+		}
+		n++
+	}
+	return n
+}
+
+// remove delete kv with lock held
+func (s *shard) remove(val *valueStruct) {
+	delete(s.val, val.key)
+	s.account(-1, -entrySize(val.key, val.value))
+	if val.elem != nil {
+		s.lru.Remove(val.elem)
+	}
+	if val.index >= 0 {
+		heap.Remove(&s.expiry, val.index)
+	}
+}
+
+// entrySize is the approximate bytes of kv
+func entrySize(key, value string) int64 {
+	return int64(len(key) + len(value) + entryOverhead)
+}
+
+// expiryHeap implements heap.Interface, kv expiring first is at index 0
+type expiryHeap []*valueStruct
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].heapTS < h[j].heapTS }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	val := x.(*valueStruct)
+	val.index = len(*h)
+	*h = append(*h, val)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	val := old[len(old)-1]
+	old[len(old)-1] = nil
+	val.index = -1
+	*h = old[:len(old)-1]
+	return val
 }
 
 // Ping return nil
 func (c *DefaultCache) Ping() error { return nil }
 
-// Close will simply close done channel.
-// all goroutines should monitor done channel and exit when done channel closed
+// Close will close done channel and wait for cronJob to exit.
+// all goroutines should monitor done channel and exit when done channel closed.
+// Close can be called more than once, cache can still be used after Close, expired kv are removed by Get and GetSize only
 func (c *DefaultCache) Close() {
-	close(c.done)
+	c.closeOnce.Do(func() { close(c.done) })
+	c.wg.Wait()
 }
 
 // IncrCounter will increment hit counter
 func (c *DefaultCache) IncrCounter() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.hit++
+	atomic.AddInt64(&c.hit, 1)
 }
 
 // GetCounter will return hit counter
 func (c *DefaultCache) GetCounter() int {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	count := c.hit
-	return count
+	return int(atomic.LoadInt64(&c.hit))
+}
+
+// GetEvictions return number of kv evicted by bound and removed once expired
+func (c *DefaultCache) GetEvictions() (int, int) {
+	var evicted, expired int64
+	for _, s := range c.shards {
+		evicted += atomic.LoadInt64(&s.evicted)
+		expired += atomic.LoadInt64(&s.expired)
+	}
+	return int(evicted), int(expired)
 }
 
-// GetSize return number of keys
-// This might not be accurate since the cronJob run every minute
+// GetSize return number of live keys, expired ones are removed first
 func (c *DefaultCache) GetSize() int {
-	return len(c.val)
+	now := c.clock().UnixNano()
+	size := 0
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		s.sweep(now, 0)
+		size += len(s.val)
+		s.mutex.Unlock()
+	}
+	return size
 }
 
-// Flush assign new map to val
+// Flush assign new map to each shard, one shard at a time.
+// kv set to a shard already flushed while Flush runs are kept
 func (c *DefaultCache) Flush() {
-	c.val = make(map[string]*valueStruct)
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		s.account(-int64(len(s.val)), -s.bytes)
+		s.val = make(map[string]*valueStruct)
+		s.expiry = nil
+		if s.lru != nil {
+			s.lru.Init()
+		}
+		s.mutex.Unlock()
+	}
 }
 
 // cronJob will run periodically in background
-// scan all values in map and check if they are expired
-// if so, delete them
+// remove expired kv of each shard, lock of a shard is held only while at most sweepBatch due kv are visited
 func (c *DefaultCache) cronJob() {
-	// scan the map every 5 second
-	tickCh := time.NewTicker(5 * time.Second)
+	defer c.wg.Done()
+	tickCh := time.NewTicker(c.sweepInterval)
 
 	for {
 		select {
@@ -116,25 +464,36 @@ func (c *DefaultCache) cronJob() {
 			tickCh.Stop()
 			return
 		case <-tickCh.C:
-			c.mutex.Lock()
-			for k, v := range c.val {
-				if isExpired(v.expTS) {
-					delete(c.val, k)
+			now := c.clock().UnixNano()
+			for _, s := range c.shards {
+				for n := sweepBatch; n == sweepBatch; {
+					s.mutex.Lock()
+					n = s.sweep(now, sweepBatch)
+					s.mutex.Unlock()
+					// let requests waiting for the shard run, even on a single CPU
+					runtime.Gosched()
 				}
 			}
-			c.mutex.Unlock()
-
 		}
 	}
 }
 
-func isExpired(expTS int64) bool {
-	if expTS < time.Now().Unix() {
+// expiration return expiration ts of ttl from now, 0 if ttl is 0
+func expiration(now int64, ttl time.Duration) int64 {
+	if ttl == 0 {
+		return 0
+	}
+	return now + int64(ttl)
+}
+
+// isExpired checks expTS at now, 0 never expires
+func isExpired(expTS, now int64) bool {
+	if expTS != 0 && expTS <= now {
 		return true
 	}
 	return false
 }
 
-func stringToInt(s string) (int, error) {
-	return strconv.Atoi(s)
+func stringToInt(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
 }
@@ -1,93 +1,170 @@
 package cacheMe
 
 import (
+	"container/heap"
 	"reflect"
-	"sync"
+	"strconv"
 	"testing"
 	"time"
 )
 
-// get test DefaultCache without setting map
-// map will be set on each test case
+// testNow is the time of DefaultCache in tests
+var testNow = time.Unix(1600000000, 0)
+
+// get test DefaultCache of a single shard without cronJob, clock is always testNow
+// kv will be set on each test case by setVal
 func getDC() *DefaultCache {
-	return &DefaultCache{
-		mutex: &sync.Mutex{},
-		done:  make(chan struct{}),
+	dc := newDefaultCache(Policy{}, Shards(1))
+	dc.clock = func() time.Time { return testNow }
+	return dc
+}
+
+// setVal replace kv of all shards with vals
+func setVal(dc *DefaultCache, vals map[string]*valueStruct) {
+	dc.Flush()
+	for key, val := range vals {
+		s := dc.shardOf(key)
+		s.val[key] = val
+		if val.expTS != 0 {
+			val.heapTS = val.expTS
+			heap.Push(&s.expiry, val)
+		}
+	}
+}
+
+// getVal return copy of kv of all shards without LRU element and heap position, to compare with cachedVal
+func getVal(dc *DefaultCache) map[string]*valueStruct {
+	vals := emptyVal()
+	for _, s := range dc.shards {
+		for key, val := range s.val {
+			v := *val
+			v.elem, v.usedSeq, v.heapTS, v.index = nil, 0, 0, -1
+			vals[key] = &v
+		}
 	}
+	return vals
 }
 
 func emptyVal() map[string]*valueStruct {
 	return make(map[string]*valueStruct)
 }
 
-func cachedVal(key string, value int, backOffSecond int64) map[string]*valueStruct {
+// cachedVal return kv set at testNow, expiring backOffSecond later
+func cachedVal(key string, value string, backOffSecond int64) map[string]*valueStruct {
 	return map[string]*valueStruct{
 		key: &valueStruct{
+			key:   key,
 			value: value,
-			expTS: time.Now().Unix() + backOffSecond,
+			expTS: testNow.Add(time.Duration(backOffSecond) * time.Second).UnixNano(),
+			setTS: testNow.UnixNano(),
+			index: -1,
 		},
 	}
 }
 
+// persistentVal return kv set at testNow, never expires
+func persistentVal(key string, value string) map[string]*valueStruct {
+	return map[string]*valueStruct{key: &valueStruct{key: key, value: value, setTS: testNow.UnixNano(), index: -1}}
+}
+
 func TestDCGet(t *testing.T) {
 	dc := getDC()
 	cases := []struct {
 		name     string
 		existVal map[string]*valueStruct
 		getKey   string
-		expInt   int
+		ttl      time.Duration
+		expStr   string
 		expBool  bool
 		expVal   map[string]*valueStruct
 	}{
 		{
 			name: "not cached", existVal: emptyVal(), getKey: "foo",
-			expInt: 0, expBool: false, expVal: emptyVal(),
+			expStr: "", expBool: false, expVal: emptyVal(),
 		},
 		{
-			name: "cached", existVal: cachedVal("foo", 1, 30), getKey: "foo",
-			expInt: 1, expBool: true, expVal: cachedVal("foo", 1, 90),
+			name: "cached", existVal: cachedVal("foo", "1", 30), getKey: "foo", ttl: time.Minute,
+			expStr: "1", expBool: true, expVal: cachedVal("foo", "1", 60),
 		},
 		{
-			name: "expired", existVal: cachedVal("foo", 1, -30), getKey: "foo",
-			expInt: 0, expBool: false, expVal: emptyVal(),
+			name: "no expiry", existVal: cachedVal("foo", "1", 30), getKey: "foo",
+			expStr: "1", expBool: true, expVal: persistentVal("foo", "1"),
+		},
+		{
+			name: "renew no expiry", existVal: persistentVal("foo", "1"), getKey: "foo", ttl: time.Minute,
+			expStr: "1", expBool: true, expVal: cachedVal("foo", "1", 60),
+		},
+		{
+			name: "expire now", existVal: cachedVal("foo", "1", 0), getKey: "foo",
+			expStr: "", expBool: false, expVal: emptyVal(),
+		},
+		{
+			name: "expired", existVal: cachedVal("foo", "1", -30), getKey: "foo",
+			expStr: "", expBool: false, expVal: emptyVal(),
 		},
 	}
 
 	for _, c := range cases {
-		dc.val = c.existVal
-		gotInt, gotBool := dc.Get(c.getKey)
-		if gotInt != c.expInt {
-			t.Errorf("error on: %v\ngot int:\n %v \nexp int\n %v \n", c.name, gotInt, c.expInt)
+		setVal(dc, c.existVal)
+		gotStr, gotBool := dc.Get(c.getKey, c.ttl)
+		if gotStr != c.expStr {
+			t.Errorf("error on: %v\ngot str:\n %v \nexp str\n %v \n", c.name, gotStr, c.expStr)
 		}
 		if gotBool != c.expBool {
 			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
 		}
-		if reflect.DeepEqual(dc.val, c.expVal) == false {
-			t.Errorf("error on: %v asserting val\ngot val:\n %v \nexp val\n %v \n", c.name, dc.val, c.expVal)
+		if reflect.DeepEqual(getVal(dc), c.expVal) == false {
+			t.Errorf("error on: %v asserting val\ngot val:\n %v \nexp val\n %v \n", c.name, getVal(dc), c.expVal)
 		}
 	}
 }
 
+func TestDCMGet(t *testing.T) {
+	dc := getDC()
+	setVal(dc, map[string]*valueStruct{
+		"foo":     cachedVal("foo", "1", 30)["foo"],
+		"expired": cachedVal("expired", "2", -30)["expired"],
+	})
+	gotVals, gotBools := dc.MGet([]string{"foo", "bar", "expired"}, []time.Duration{time.Minute, time.Minute, time.Minute})
+	expVals, expBools := []string{"1", "", ""}, []bool{true, false, false}
+	if reflect.DeepEqual(gotVals, expVals) == false || reflect.DeepEqual(gotBools, expBools) == false {
+		t.Errorf("error on: mget\ngot:\n %v %v \nexp\n %v %v \n", gotVals, gotBools, expVals, expBools)
+	}
+	expVal := cachedVal("foo", "1", 60)
+	if reflect.DeepEqual(getVal(dc), expVal) == false {
+		t.Errorf("error on: mget asserting val\ngot val:\n %v \nexp val\n %v \n", getVal(dc), expVal)
+	}
+}
+
 func TestDCSetWithTTL(t *testing.T) {
 	dc := getDC()
 	cases := []struct {
 		name     string
 		existVal map[string]*valueStruct
 		setKey   string
-		setVal   int
+		setVal   string
+		ttl      time.Duration
 		expVal   map[string]*valueStruct
 	}{
 		{
 			name: "case 1", existVal: emptyVal(), setKey: "foo",
-			setVal: 1, expVal: cachedVal("foo", 1, 60),
+			setVal: "1", ttl: time.Minute, expVal: cachedVal("foo", "1", 60),
+		},
+		{
+			name: "custom ttl", existVal: emptyVal(), setKey: "foo",
+			setVal: "1", ttl: time.Hour, expVal: cachedVal("foo", "1", 3600),
+		},
+		{
+			name: "no expiry", existVal: cachedVal("foo", "2", 30), setKey: "foo",
+			setVal: "1", expVal: persistentVal("foo", "1"),
 		},
 	}
 
 	for _, c := range cases {
-		dc.val = c.existVal
-		dc.SetWithTTL(c.setKey, c.setVal)
-		if reflect.DeepEqual(dc.val, c.expVal) == false {
-			t.Errorf("error on: %v asserting val\ngot val:\n %v \nexp val\n %v \n", c.name, dc.val, c.expVal)
+		setVal(dc, c.existVal)
+		dc.SetWithTTL(c.setKey, c.setVal, c.ttl)
+		if reflect.DeepEqual(getVal(dc), c.expVal) == false {
+			t.Errorf("error on: %v asserting val\ngot val:\n %v \nexp val\n %v \n", c.name, getVal(dc), c.expVal)
 		}
 	}
 }
@@ -117,11 +194,12 @@ func TestDCGetSize(t *testing.T) {
 		expSize  int
 	}{
 		{name: "case 1", existVal: emptyVal(), expSize: 0},
-		{name: "case 2", existVal: cachedVal("foo", 1, 30), expSize: 1},
+		{name: "case 2", existVal: cachedVal("foo", "1", 30), expSize: 1},
+		{name: "case expired", existVal: cachedVal("foo", "1", -30), expSize: 0},
 	}
 
 	for _, c := range cases {
-		dc.val = c.existVal
+		setVal(dc, c.existVal)
 		gotSize := dc.GetSize()
 		if gotSize != c.expSize {
 			t.Errorf("error on: %v\ngot size:\n %v \nexp size\n %v \n", c.name, gotSize, c.expSize)
@@ -129,6 +207,188 @@ func TestDCGetSize(t *testing.T) {
 	}
 }
 
+func TestDCLRU(t *testing.T) {
+	size := entrySize("a", "1")
+	cases := []struct {
+		name string
+		opts []Option
+		// keys are set in order, get is looked up before the last key is set
+		keys, get, expKeys []string
+		expEvicted         int
+	}{
+		{name: "unbounded", keys: []string{"a", "b", "c"}, expKeys: []string{"a", "b", "c"}},
+		{name: "max entries", opts: []Option{MaxEntries(2)}, keys: []string{"a", "b", "c"}, expKeys: []string{"b", "c"}, expEvicted: 1},
+		{name: "recently used", opts: []Option{MaxEntries(2)}, keys: []string{"a", "b", "c"}, get: []string{"a"}, expKeys: []string{"a", "c"}, expEvicted: 1},
+		{name: "set again", opts: []Option{MaxEntries(2)}, keys: []string{"a", "b", "a", "c"}, expKeys: []string{"a", "c"}, expEvicted: 1},
+		{name: "max bytes", opts: []Option{MaxBytes(2*size + 1)}, keys: []string{"a", "b", "c"}, expKeys: []string{"b", "c"}, expEvicted: 1},
+		{name: "both", opts: []Option{MaxEntries(1), MaxBytes(10 * size)}, keys: []string{"a", "b", "c"}, expKeys: []string{"c"}, expEvicted: 2},
+		{name: "larger than max bytes", opts: []Option{MaxBytes(size - 1)}, keys: []string{"a"}, expKeys: []string{}, expEvicted: 1},
+	}
+	for _, c := range cases {
+		dc := NewDefaultClient(Policy{}, append(c.opts, Shards(1))...)
+		for i, key := range c.keys {
+			if i == len(c.keys)-1 {
+				for _, g := range c.get {
+					dc.Get(g, time.Minute)
+				}
+			}
+			dc.SetWithTTL(key, "1", time.Minute)
+		}
+		gotKeys := []string{}
+		for _, key := range []string{"a", "b", "c"} {
+			if _, ok := dc.shards[0].val[key]; ok {
+				gotKeys = append(gotKeys, key)
+			}
+		}
+		gotEvicted, _ := dc.GetEvictions()
+		if reflect.DeepEqual(gotKeys, c.expKeys) == false || gotEvicted != c.expEvicted {
+			t.Errorf("error on: %v\ngot keys and evicted:\n %v %v \nexp keys and evicted\n %v %v \n", c.name, gotKeys, gotEvicted, c.expKeys, c.expEvicted)
+		}
+		if gotBytes := int64(len(gotKeys)) * size; dc.shards[0].bytes != gotBytes {
+			t.Errorf("error on: %v\ngot bytes:\n %v \nexp bytes\n %v \n", c.name, dc.shards[0].bytes, gotBytes)
+		}
+		dc.Close()
+	}
+}
+
+// TestDCGlobalBound checks bound is of all shards, the least recently used kv of all shards is evicted
+func TestDCGlobalBound(t *testing.T) {
+	// kv of many keys below are the largest
+	size := entrySize("add:999:1", "1")
+	cases := []struct {
+		name string
+		opts []Option
+		// keys are set in order, get is looked up before the last key is set
+		keys, get, expKeys []string
+	}{
+		{name: "max entries", opts: []Option{MaxEntries(3)}, keys: []string{"a", "b", "c", "d", "e"}, expKeys: []string{"c", "d", "e"}},
+		{name: "max bytes", opts: []Option{MaxBytes(3*size + 1)}, keys: []string{"a", "b", "c", "d", "e"}, expKeys: []string{"c", "d", "e"}},
+		{name: "recently used", opts: []Option{MaxEntries(4)}, keys: []string{"a", "b", "c", "d", "e"}, get: []string{"a"}, expKeys: []string{"a", "c", "d", "e"}},
+	}
+	for _, c := range cases {
+		dc := NewDefaultClient(Policy{}, c.opts...)
+		// keys of the same size, spread over shards
+		for i, key := range c.keys {
+			if i == len(c.keys)-1 {
+				for _, g := range c.get {
+					dc.Get("add:"+g+":1", time.Minute)
+				}
+			}
+			dc.SetWithTTL("add:"+key+":1", "1", time.Minute)
+		}
+		gotKeys := []string{}
+		for _, key := range []string{"a", "b", "c", "d", "e"} {
+			if _, ok := dc.shardOf("add:" + key + ":1").val["add:"+key+":1"]; ok {
+				gotKeys = append(gotKeys, key)
+			}
+		}
+		gotEvicted, _ := dc.GetEvictions()
+		expEvicted := len(c.keys) - len(c.expKeys)
+		if reflect.DeepEqual(gotKeys, c.expKeys) == false || gotEvicted != expEvicted {
+			t.Errorf("error on: %v\ngot keys and evicted:\n %v %v \nexp keys and evicted\n %v %v \n", c.name, gotKeys, gotEvicted, c.expKeys, expEvicted)
+		}
+
+		// many keys never exceed bound
+		for i := 100; i < 1000; i++ {
+			dc.SetWithTTL("add:"+strconv.Itoa(i)+":1", "1", time.Minute)
+		}
+		if gotSize := dc.GetSize(); gotSize != len(c.expKeys) {
+			t.Errorf("error on: %v, many keys\ngot size:\n %v \nexp size\n %v \n", c.name, gotSize, len(c.expKeys))
+		}
+		dc.Close()
+	}
+}
+
+func TestDCExpiredCounter(t *testing.T) {
+	dc := getDC()
+	setVal(dc, cachedVal("foo", "1", -30))
+	dc.Get("foo", time.Minute)
+	evicted, expired := dc.GetEvictions()
+	if evicted != 0 || expired != 1 {
+		t.Errorf("error on: expired\ngot evicted and expired:\n %v %v \nexp evicted and expired\n %v %v \n", evicted, expired, 0, 1)
+	}
+}
+
+func TestDCSweep(t *testing.T) {
+	now := testNow
+	dc := getDC()
+	dc.clock = func() time.Time { return now }
+	dc.SetWithTTL("a", "1", 10*time.Second)
+	dc.SetWithTTL("b", "1", 20*time.Second)
+	dc.SetWithTTL("c", "1", 30*time.Second)
+	dc.SetWithTTL("forever", "1", 0)
+	dc.SetWithTTL("d", "1", time.Hour)
+	// a is renewed to expire after b, c never expires once renewed with 0 ttl.
+	// both stay at their heapTS until it is due, d is renewed to expire earlier and moved at once
+	now = now.Add(5 * time.Second)
+	dc.Get("a", 20*time.Second)
+	dc.Get("c", 0)
+	dc.Get("d", time.Second)
+
+	cases := []struct {
+		name    string
+		wait    time.Duration
+		expKeys []string
+		expHeap int
+	}{
+		{name: "none due", wait: 10 * time.Second, expKeys: []string{"a", "b", "c", "forever"}, expHeap: 3},
+		{name: "b due", wait: 5 * time.Second, expKeys: []string{"a", "c", "forever"}, expHeap: 2},
+		{name: "a due", wait: 5 * time.Second, expKeys: []string{"c", "forever"}, expHeap: 1},
+		{name: "no expiry", wait: time.Hour, expKeys: []string{"c", "forever"}, expHeap: 0},
+	}
+	for _, c := range cases {
+		now = now.Add(c.wait)
+		dc.shards[0].sweep(now.UnixNano(), 0)
+		gotKeys := []string{}
+		for _, key := range []string{"a", "b", "c", "d", "forever"} {
+			if _, ok := dc.shards[0].val[key]; ok {
+				gotKeys = append(gotKeys, key)
+			}
+		}
+		if reflect.DeepEqual(gotKeys, c.expKeys) == false || len(dc.shards[0].expiry) != c.expHeap {
+			t.Errorf("error on: %v\ngot keys and heap:\n %v %v \nexp keys and heap\n %v %v \n", c.name, gotKeys, len(dc.shards[0].expiry), c.expKeys, c.expHeap)
+		}
+	}
+	if _, expired := dc.GetEvictions(); expired != 3 {
+		t.Errorf("error on: sweep\ngot expired:\n %v \nexp expired\n %v \n", expired, 3)
+	}
+}
+
+func TestDCShards(t *testing.T) {
+	cases := []struct {
+		name      string
+		opts      []Option
+		keys      int
+		expShards int
+		expSize   int
+	}{
+		{name: "default", keys: 1000, expShards: DefaultShards, expSize: 1000},
+		{name: "invalid", opts: []Option{Shards(-1)}, keys: 1000, expShards: DefaultShards, expSize: 1000},
+		{name: "max entries of all shards", opts: []Option{Shards(4), MaxEntries(10)}, keys: 1000, expShards: 4, expSize: 10},
+	}
+	for _, c := range cases {
+		dc := newDefaultCache(Policy{}, c.opts...)
+		if len(dc.shards) != c.expShards {
+			t.Errorf("error on: %v\ngot shards:\n %v \nexp shards\n %v \n", c.name, len(dc.shards), c.expShards)
+		}
+		used := map[*shard]bool{}
+		for i := 0; i < c.keys; i++ {
+			key := "add:" + strconv.Itoa(i) + ":1"
+			dc.SetWithTTL(key, "1", time.Minute)
+			used[dc.shardOf(key)] = true
+			if dc.shardOf(key) != dc.shardOf(key) {
+				t.Errorf("error on: %v\nshard of %v is not stable\n", c.name, key)
+			}
+		}
+		if c.keys >= 1000 && len(used) != c.expShards {
+			t.Errorf("error on: %v\ngot used shards:\n %v \nexp used shards\n %v \n", c.name, len(used), c.expShards)
+		}
+		if gotSize := dc.GetSize(); gotSize != c.expSize {
+			t.Errorf("error on: %v\ngot size:\n %v \nexp size\n %v \n", c.name, gotSize, c.expSize)
+		}
+	}
+}
+
 func TestDCFlush(t *testing.T) {
 	dc := getDC()
 	cases := []struct {
@@ -137,14 +397,14 @@ func TestDCFlush(t *testing.T) {
 		expVal   map[string]*valueStruct
 	}{
 		{name: "case 1", existVal: emptyVal(), expVal: emptyVal()},
-		{name: "case 2", existVal: cachedVal("foo", 1, 30), expVal: emptyVal()},
+		{name: "case 2", existVal: cachedVal("foo", "1", 30), expVal: emptyVal()},
 	}
 
 	for _, c := range cases {
-		dc.val = c.existVal
+		setVal(dc, c.existVal)
 		dc.Flush()
-		if reflect.DeepEqual(dc.val, c.expVal) == false {
-			t.Errorf("error on: %v asserting val\ngot val:\n %v \nexp val\n %v \n", c.name, dc.val, c.expVal)
+		if reflect.DeepEqual(getVal(dc), c.expVal) == false {
+			t.Errorf("error on: %v asserting val\ngot val:\n %v \nexp val\n %v \n", c.name, getVal(dc), c.expVal)
 		}
 	}
 }
@@ -155,12 +415,13 @@ func TestIsExpired(t *testing.T) {
 		expTS   int64
 		expBool bool
 	}{
-		{name: "case 1", expTS: time.Now().Unix(), expBool: false},
-		{name: "case 2", expTS: time.Now().Unix() + 10, expBool: false},
-		{name: "case 3", expTS: time.Now().Unix() - 10, expBool: true},
+		{name: "case 1", expTS: testNow.UnixNano(), expBool: true},
+		{name: "case 2", expTS: testNow.Add(10 * time.Second).UnixNano(), expBool: false},
+		{name: "case 3", expTS: testNow.Add(-10 * time.Second).UnixNano(), expBool: true},
+		{name: "no expiry", expTS: 0, expBool: false},
 	}
 	for _, c := range cases {
-		gotBool := isExpired(c.expTS)
+		gotBool := isExpired(c.expTS, testNow.UnixNano())
 		if gotBool != c.expBool {
 			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
 		}
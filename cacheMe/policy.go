@@ -0,0 +1,55 @@
+package cacheMe
+
+import (
+	"fmt"
+	"time"
+)
+
+// expiration of Policy
+const (
+	// Sliding reset expiration to TTL from now on every hit
+	Sliding = "sliding"
+	// Absolute keep expiration set by SetWithTTL, hit doesn't renew it
+	Absolute = "absolute"
+)
+
+// ErrExpiration is returned by NewPolicy if expiration is unknown
+var ErrExpiration = fmt.Errorf("Invalid expiration. %v or %v only", Sliding, Absolute)
+
+// ErrMaxLifetime is returned by NewPolicy if max lifetime is negative
+var ErrMaxLifetime = fmt.Errorf("Invalid max lifetime. Duration no less than 0 only, 0 is no limit")
+
+// Policy is how keys expire, DefaultCache and RedisClient behave the same.
+// key expires once TTL passed since it was set, or last hit if expiration is Sliding.
+// MaxLifetime caps lifetime of a key since it was set, no matter how often it is hit.
+// 0 TTL never expires unless MaxLifetime is set, 0 MaxLifetime is no cap.
+// zero value is Sliding without cap
+type Policy struct {
+	Expiration  string
+	MaxLifetime time.Duration
+}
+
+// NewPolicy return Policy of expiration, empty expiration is Sliding
+func NewPolicy(expiration string, maxLifetime time.Duration) (Policy, error) {
+	if expiration != "" && expiration != Sliding && expiration != Absolute {
+		return Policy{}, ErrExpiration
+	}
+	if maxLifetime < 0 {
+		return Policy{}, ErrMaxLifetime
+	}
+	return Policy{Expiration: expiration, MaxLifetime: maxLifetime}, nil
+}
+
+// sliding checks if hit renews expiration
+func (p Policy) sliding() bool {
+	return p.Expiration != Absolute
+}
+
+// capped return ttl capped by remaining lifetime of a key, 0 is no expiry.
+// remaining is ignored if there is no MaxLifetime
+func (p Policy) capped(ttl, remaining time.Duration) time.Duration {
+	if p.MaxLifetime == 0 || (ttl != 0 && ttl <= remaining) {
+		return ttl
+	}
+	return remaining
+}
@@ -8,20 +8,25 @@ import (
 
 var redisCounter = "hit"
 
+// lifetimePrefix is prefix of the key whose TTL is the remaining lifetime of a key,
+// it is set only if policy is sliding with MaxLifetime
+const lifetimePrefix = "lifetime:"
+
 // RedisClient implemented cacheClient interface
-// use redis as cache backend
+// use redis as cache backend, keys expire by policy
 type RedisClient struct {
 	client *redis.Client
+	policy Policy
 }
 
-// NewRedisClient return a new RedisClient
-func NewRedisClient(redisURL string) *RedisClient {
+// NewRedisClient return a new RedisClient, keys expire by policy
+func NewRedisClient(redisURL string, policy Policy) *RedisClient {
 	opt, err := redis.ParseURL(redisURL)
 	if err != nil {
 		panic(err)
 	}
 	c := redis.NewClient(opt)
-	return &RedisClient{client: c}
+	return &RedisClient{client: c, policy: policy}
 }
 
 // Close will close connection
@@ -29,46 +34,108 @@ func (c *RedisClient) Close() {
 	c.client.Close()
 }
 
-// Get will use pipeline, return value, true if key exist, otherwise 0, false
-// get the value if cached and extend TTL for 60 seconds
-func (c *RedisClient) Get(key string) (int, bool) {
+// Get return value, true if key exist, otherwise "", false
+// get the value if cached and renew TTL to ttl if policy is sliding, see MGet
+func (c *RedisClient) Get(key string, ttl time.Duration) (string, bool) {
+	values, oks := c.MGet([]string{key}, []time.Duration{ttl})
+	return values[0], oks[0]
+}
 
-	pipe := c.client.TxPipeline()
+// MGet is the batch version of Get.
+// GET and PEXPIRE of all keys are sent in a single pipeline, so that
+// there is only one round trip no matter how many keys there are.
+// if lifetime is capped, PTTL of lifetime keys is sent instead of PEXPIRE,
+// TTL of hits is renewed in a second round trip.
+// TTL of keys[i] is renewed to ttls[i], values and bools are in the same order of keys
+func (c *RedisClient) MGet(keys []string, ttls []time.Duration) ([]string, []bool) {
+	values := make([]string, len(keys))
+	oks := make([]bool, len(keys))
+	if len(keys) == 0 {
+		return values, oks
+	}
 
-	g := pipe.Get(key)
-	pipe.Expire(key, time.Minute)
+	capped := c.policy.sliding() && c.policy.MaxLifetime != 0
+	pipe := c.client.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	lifetimes := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipe.Get(key)
+		if capped {
+			lifetimes[i] = pipe.PTTL(lifetimePrefix + key)
+		} else if c.policy.sliding() {
+			expire(pipe, key, ttls[i])
+		}
+	}
 
+	// err is redis.Nil if any key doesn't exist, result of each key is checked below
 	_, err := pipe.Exec()
-	if err == redis.Nil {
-		return 0, false
-	} else if err != nil {
-		fmt.Printf("get key err: %v\n", err)
+	if err != nil && err != redis.Nil {
+		fmt.Printf("mget keys err: %v\n", err)
 	}
 
-	val, err := g.Result()
-	if err == redis.Nil {
-		fmt.Println("got nil")
-		return 0, false
-	} else if err != nil {
-		fmt.Printf("get key err: %v\n", err)
+	for i, g := range gets {
+		val, err := g.Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			fmt.Printf("get key err: %v\n", err)
+			continue
+		}
+		values[i], oks[i] = val, true
+	}
+	if capped {
+		c.renew(keys, ttls, lifetimes, values, oks)
 	}
+	return values, oks
+}
 
-	v, err := stringToInt(val)
+// renew TTL of hits to ttls capped by their remaining lifetime.
+// key without remaining lifetime is deleted and becomes a miss
+func (c *RedisClient) renew(keys []string, ttls []time.Duration, lifetimes []*redis.DurationCmd, values []string, oks []bool) {
+	pipe := c.client.Pipeline()
+	for i, key := range keys {
+		if oks[i] == false {
+			continue
+		}
+		// PTTL is negative if lifetime key doesn't exist or never expires
+		remaining := lifetimes[i].Val()
+		if remaining <= 0 {
+			pipe.Del(key)
+			values[i], oks[i] = "", false
+			continue
+		}
+		expire(pipe, key, c.policy.capped(ttls[i], remaining))
+	}
+	_, err := pipe.Exec()
 	if err != nil {
-		return 0, false
+		fmt.Printf("renew keys err: %v\n", err)
 	}
-	return v, true
 }
 
-// SetWithTTL will set kv in redis with TTL
-func (c *RedisClient) SetWithTTL(key string, value int) {
-	err := c.client.Set(key, value, time.Minute).Err()
+// SetWithTTL will set kv in redis with TTL, 0 ttl is no expiry.
+// lifetime key is set as well if policy is sliding with MaxLifetime
+func (c *RedisClient) SetWithTTL(key string, value string, ttl time.Duration) {
+	pipe := c.client.TxPipeline()
+	pipe.Set(key, value, c.policy.capped(ttl, c.policy.MaxLifetime))
+	if c.policy.sliding() && c.policy.MaxLifetime != 0 {
+		pipe.Set(lifetimePrefix+key, "", c.policy.MaxLifetime)
+	}
+	_, err := pipe.Exec()
 	if err != nil {
 		fmt.Printf("set key err: %v\n", err)
 	}
 
 }
 
+// expire queue PEXPIRE of key to pipe, or PERSIST if ttl is 0
+func expire(pipe redis.Pipeliner, key string, ttl time.Duration) {
+	if ttl == 0 {
+		pipe.Persist(key)
+		return
+	}
+	pipe.PExpire(key, ttl)
+}
+
 // Ping will ping redis
 func (c *RedisClient) Ping() error {
 	err := c.client.Ping().Err()
@@ -87,10 +154,10 @@ func (c *RedisClient) IncrCounter() {
 func (c *RedisClient) GetCounter() int {
 	val := c.client.Get(redisCounter).Val()
 	v, _ := stringToInt(val)
-	return v
+	return int(v)
 }
 
-// GetSize will return size of DB, including counter
+// GetSize will return size of DB, including counter and lifetime keys
 func (c *RedisClient) GetSize() int {
 	val := c.client.DBSize().Val()
 	return int(val)
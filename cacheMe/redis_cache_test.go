@@ -14,25 +14,25 @@ func TestGet(t *testing.T) {
 	defer s.Close()
 
 	add := "redis://" + s.Addr()
-	redisC := NewRedisClient(add)
+	redisC := NewRedisClient(add, Policy{})
 
 	s.Set("foo", "5")
 	s.SetTTL("foo", 60*time.Second)
-	s.Set("bar", "a")
+	s.Set("bar", "123456789012345678901234567890")
 
 	cases := []struct {
 		name    string
 		key     string
-		expVal  int
+		expVal  string
 		expBool bool
 	}{
-		{name: "case 1", key: "foo", expVal: 5, expBool: true},
-		{name: "case 2", key: "bar", expVal: 0, expBool: false},
-		{name: "case 3", key: "foobar", expVal: 0, expBool: false},
+		{name: "case 1", key: "foo", expVal: "5", expBool: true},
+		{name: "case 2", key: "bar", expVal: "123456789012345678901234567890", expBool: true},
+		{name: "case 3", key: "foobar", expVal: "", expBool: false},
 	}
 
 	for _, c := range cases {
-		gotVal, gotBool := redisC.Get(c.key)
+		gotVal, gotBool := redisC.Get(c.key, time.Minute)
 		if gotVal != c.expVal {
 			t.Errorf("error on: %v\ngot val:\n %v \nexp val\n %v \n", c.name, gotVal, c.expVal)
 		}
@@ -42,6 +42,44 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestMGet(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	add := "redis://" + s.Addr()
+	redisC := NewRedisClient(add, Policy{})
+
+	s.Set("foo", "5")
+	s.SetTTL("foo", 30*time.Second)
+	s.Set("bar", "-1")
+	s.SetTTL("bar", 30*time.Second)
+
+	gotVals, gotBools := redisC.MGet([]string{"foo", "foobar", "bar"}, []time.Duration{time.Minute, time.Minute, 0})
+	expVals, expBools := []string{"5", "", "-1"}, []bool{true, false, true}
+	for i := range expVals {
+		if gotVals[i] != expVals[i] || gotBools[i] != expBools[i] {
+			t.Errorf("error on: key %v\ngot:\n %v %v \nexp\n %v %v \n", i, gotVals[i], gotBools[i], expVals[i], expBools[i])
+		}
+	}
+	if ttl := s.TTL("foo"); ttl != time.Minute {
+		t.Errorf("error on: renew ttl\ngot ttl:\n %v \nexp ttl\n %v \n", ttl, time.Minute)
+	}
+	if ttl := s.TTL("bar"); ttl != 0 {
+		t.Errorf("error on: persist\ngot ttl:\n %v \nexp ttl\n %v \n", ttl, 0)
+	}
+	if s.Exists("foobar") {
+		t.Errorf("error on: missing key should not be created")
+	}
+
+	gotVals, gotBools = redisC.MGet(nil, nil)
+	if len(gotVals) != 0 || len(gotBools) != 0 {
+		t.Errorf("error on: no keys\ngot:\n %v %v \n", gotVals, gotBools)
+	}
+}
+
 func TestSetWithTTL(t *testing.T) {
 	s, err := miniredis.Run()
 	if err != nil {
@@ -50,7 +88,7 @@ func TestSetWithTTL(t *testing.T) {
 	defer s.Close()
 
 	add := "redis://" + s.Addr()
-	redisC := NewRedisClient(add)
+	redisC := NewRedisClient(add, Policy{})
 
 	s.Set("foo", "5")
 	s.SetTTL("foo", 30*time.Second)
@@ -58,22 +96,22 @@ func TestSetWithTTL(t *testing.T) {
 	cases := []struct {
 		name string
 		key  string
-		val  int
-		sec  int
+		val  string
+		ttl  time.Duration
 	}{
-		{name: "case 1", key: "foo", val: 5},
-		{name: "case 2", key: "bar", val: 0},
-		{name: "case 3", key: "foo", val: 2},
+		{name: "case 1", key: "foo", val: "5", ttl: time.Minute},
+		{name: "case 2", key: "bar", val: "0", ttl: time.Hour},
+		{name: "case 3", key: "foo", val: "2", ttl: 0},
 	}
 
 	for _, c := range cases {
-		redisC.SetWithTTL(c.key, c.val)
+		redisC.SetWithTTL(c.key, c.val, c.ttl)
 		ttl := s.TTL(c.key)
 		if s.Exists(c.key) == false {
 			t.Errorf("error on: %v\nkey: %v not set", c.name, c.key)
 		}
-		if ttl != time.Minute {
-			t.Errorf("error on: %v\ngot ttl:\n %v \nexp ttl\n %v \n", c.name, ttl, time.Minute)
+		if ttl != c.ttl {
+			t.Errorf("error on: %v\ngot ttl:\n %v \nexp ttl\n %v \n", c.name, ttl, c.ttl)
 		}
 	}
 }
@@ -85,7 +123,7 @@ func TestIncrCounter(t *testing.T) {
 	}
 	defer s.Close()
 	add := "redis://" + s.Addr()
-	redisC := NewRedisClient(add)
+	redisC := NewRedisClient(add, Policy{})
 	s.Set(redisCounter, "5")
 
 	redisC.IncrCounter()
@@ -105,7 +143,7 @@ func TestGetCounter(t *testing.T) {
 	}
 	defer s.Close()
 	add := "redis://" + s.Addr()
-	redisC := NewRedisClient(add)
+	redisC := NewRedisClient(add, Policy{})
 	s.Set(redisCounter, "5")
 	got := redisC.GetCounter()
 	if got != 5 {
@@ -121,7 +159,7 @@ func TestGetSize(t *testing.T) {
 	defer s.Close()
 
 	add := "redis://" + s.Addr()
-	redisC := NewRedisClient(add)
+	redisC := NewRedisClient(add, Policy{})
 
 	s.Set("foo", "5")
 	s.Set("bar", "6")
@@ -139,7 +177,7 @@ func TestFlush(t *testing.T) {
 	defer s.Close()
 
 	add := "redis://" + s.Addr()
-	redisC := NewRedisClient(add)
+	redisC := NewRedisClient(add, Policy{})
 	s.Set("foo", "5")
 	s.Set("bar", "6")
 	redisC.Flush()
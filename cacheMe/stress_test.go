@@ -0,0 +1,156 @@
+package cacheMe
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkShards checks bookkeeping of each shard is consistent with its map,
+// and totals of all shards are within bound
+func checkShards(t *testing.T, name string, dc *DefaultCache) {
+	entries, total := 0, int64(0)
+	for i, s := range dc.shards {
+		s.mutex.Lock()
+		bytes := int64(0)
+		for key, val := range s.val {
+			bytes += entrySize(key, val.value)
+			if val.expTS != 0 && (val.index < 0 || val.heapTS > val.expTS) {
+				t.Errorf("error on: %v, shard %v\nkey %v of expTS %v is not in heap before it, heapTS %v index %v\n", name, i, key, val.expTS, val.heapTS, val.index)
+			}
+		}
+		for j, val := range s.expiry {
+			if val.index != j || (j > 0 && s.expiry[(j-1)/2].heapTS > val.heapTS) {
+				t.Errorf("error on: %v, shard %v\nheap is out of order at %v\n", name, i, j)
+			}
+		}
+		if bytes != s.bytes {
+			t.Errorf("error on: %v, shard %v\ngot bytes:\n %v \nexp bytes\n %v \n", name, i, s.bytes, bytes)
+		}
+		if s.lru != nil && s.lru.Len() != len(s.val) {
+			t.Errorf("error on: %v, shard %v\ngot lru:\n %v \nexp lru\n %v \n", name, i, s.lru.Len(), len(s.val))
+		}
+		entries, total = entries+len(s.val), total+bytes
+		s.mutex.Unlock()
+	}
+	if b := dc.bound; b != nil {
+		if int64(entries) != b.entries || total != b.bytes {
+			t.Errorf("error on: %v\ngot entries and bytes of bound:\n %v %v \nexp entries and bytes\n %v %v \n", name, b.entries, b.bytes, entries, total)
+		}
+		if b.exceeded() {
+			t.Errorf("error on: %v\ngot entries and bytes:\n %v %v \nexp no more than\n %v %v \n", name, entries, total, b.maxEntries, b.maxBytes)
+		}
+	}
+}
+
+// TestDCStress calls every method of DefaultCache from many goroutines while keys expire and are evicted,
+// run with -race to check data race
+func TestDCStress(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy Policy
+		opts   []Option
+	}{
+		{name: "sliding", policy: Policy{}, opts: []Option{Shards(4), SweepInterval(time.Millisecond)}},
+		{name: "bounded", policy: Policy{MaxLifetime: 5 * time.Millisecond}, opts: []Option{Shards(4), MaxEntries(64), SweepInterval(time.Millisecond)}},
+		{name: "absolute single shard", policy: Policy{Expiration: Absolute}, opts: []Option{Shards(1), MaxBytes(64 * entrySize("add:1:1", "1")), SweepInterval(time.Millisecond)}},
+	}
+	const goroutines, ops, keys = 8, 2000, 256
+	for _, c := range cases {
+		dc := NewDefaultClient(c.policy, c.opts...)
+		var wg sync.WaitGroup
+		var incr int64
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(seed))
+				for i := 0; i < ops; i++ {
+					key := "add:" + strconv.Itoa(r.Intn(keys)) + ":1"
+					ttl := time.Duration(r.Intn(3)) * time.Millisecond
+					switch n := r.Intn(100); {
+					case n < 40:
+						if _, ok := dc.Get(key, ttl); ok {
+							dc.IncrCounter()
+							atomic.AddInt64(&incr, 1)
+						}
+					case n < 50:
+						dc.MGet([]string{key, "add:" + strconv.Itoa(r.Intn(keys)) + ":1"}, []time.Duration{ttl, ttl})
+					case n < 85:
+						dc.SetWithTTL(key, strconv.Itoa(r.Intn(1000)), ttl)
+					case n < 90:
+						dc.GetSize()
+					case n < 95:
+						dc.GetEvictions()
+						dc.GetCounter()
+					case n < 97:
+						dc.Ping()
+					default:
+						dc.Flush()
+					}
+				}
+			}(int64(g))
+		}
+		wg.Wait()
+		checkShards(t, c.name, dc)
+		if got := dc.GetCounter(); got != int(incr) {
+			t.Errorf("error on: %v\ngot hit:\n %v \nexp hit\n %v \n", c.name, got, incr)
+		}
+
+		// Close is called concurrently with requests, more than once
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				key := "add:" + strconv.Itoa(g) + ":1"
+				dc.SetWithTTL(key, "1", time.Minute)
+				dc.Close()
+				dc.Get(key, time.Minute)
+				dc.GetSize()
+			}(g)
+		}
+		wg.Wait()
+		dc.Close()
+		checkShards(t, c.name+" closed", dc)
+	}
+}
+
+// TestDCClose checks Close waits for cronJob to exit, and can be called more than once
+func TestDCClose(t *testing.T) {
+	sweeping, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	dc := newDefaultCache(Policy{}, SweepInterval(time.Millisecond))
+	// the first sweep of cronJob blocks until released
+	dc.clock = func() time.Time {
+		once.Do(func() {
+			close(sweeping)
+			<-release
+		})
+		return time.Now()
+	}
+	dc.wg.Add(1)
+	go dc.cronJob()
+	<-sweeping
+
+	closed := make(chan struct{})
+	go func() {
+		dc.Close()
+		dc.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Errorf("error on: close\nClose returned before cronJob exits\n")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Errorf("error on: close\nClose did not return once cronJob exits\n")
+	}
+	dc.Close()
+}
@@ -1,5 +1,9 @@
 package main
 
+import (
+	"time"
+)
+
 type cacheClient interface {
 	Getter
 	Setter
@@ -10,14 +14,20 @@ type cacheClient interface {
 }
 
 // Getter interface implement method of Get
-// Get will get the value and renew TTL if key exist
+// Get will get the value and renew TTL to ttl if key exist
+// values are stored as string so that any numeric type (int64, big.Int) can be cached
+// MGet is the batch version of Get, TTL of keys[i] is renewed to ttls[i],
+// values and bools are in the same order of keys.
+// backend should look up all keys in a single round trip
 type Getter interface {
-	Get(key string) (int, bool)
+	Get(key string, ttl time.Duration) (string, bool)
+	MGet(keys []string, ttls []time.Duration) ([]string, []bool)
 }
 
 // Setter interface implement method of Set
+// ttl is how long the key is kept, 0 is no expiry
 type Setter interface {
-	SetWithTTL(key string, value int)
+	SetWithTTL(key string, value string, ttl time.Duration)
 }
 
 // Flusher implement Flush method
@@ -25,6 +35,12 @@ type Flusher interface {
 	Flush()
 }
 
+// Evicter is implemented by cache removing keys by itself, like bounded DefaultCache.
+// GetEvictions return number of keys evicted by bound and removed once expired
+type Evicter interface {
+	GetEvictions() (int, int)
+}
+
 // Counter implement IncrCounter, GetCounter and GetSize
 type Counter interface {
 	IncrCounter()
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// exit codes of client subcommands
+const (
+	exitOK = 0
+	// exitError is returned if server responded error, like invalid operand
+	exitError = 1
+	exitUsage = 2
+	// exitUnavailable is returned if server can not be reached after retries
+	exitUnavailable = 3
+)
+
+// output formats of client subcommands
+const (
+	outputHuman = "human"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+const defaultServer = "http://localhost:8000"
+
+const calcUsage = "usage: teltechcc calc [flags] <op> <operand>...\n       teltechcc calc [flags] eval <expression>"
+
+// retryBackoff is the wait before the first retry, doubled for each retry
+var retryBackoff = 200 * time.Millisecond
+
+var errOutput = fmt.Errorf("Unsupported output. %v, %v or %v only", outputHuman, outputJSON, outputCSV)
+
+// csvHeader is the header of calc and batch in CSV, see csvRow
+var csvHeader = []string{"action", "operands", "answer", "cached", "error"}
+
+// clientCommands are subcommands talking to a running server, like `teltechcc calc add 2 5`.
+// the binary starts a server if it is not one of them
+var clientCommands = map[string]func(c *client, args []string) int{
+	"calc":   (*client).calc,
+	"health": (*client).health,
+	"batch":  (*client).batch,
+}
+
+// client is a client of the server at server, output is written in format
+type client struct {
+	server  string
+	format  string
+	retries int
+	http    *http.Client
+	query   url.Values
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// runClient run subcommand args[0] with args[1:], exit code is returned
+func runClient(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	command := clientCommands[args[0]]
+	c := &client{query: url.Values{}, stdin: stdin, stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("teltechcc "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.server, "server", defaultServer, "URL of a running server")
+	fs.StringVar(&c.format, "output", outputHuman, "output format. human, json or csv")
+	fs.IntVar(&c.retries, "retries", 2, "number of retries if server can not be reached or responds 5xx or 429")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of each request")
+	params := map[string]*string{}
+	if args[0] == "calc" {
+		fs.Usage = func() {
+			fmt.Fprintln(stderr, calcUsage)
+			fs.PrintDefaults()
+		}
+		for _, name := range []string{"mode", "type", "precision", "scale", "rounding"} {
+			params[name] = fs.String(name, "", "same as query string "+name+" of the endpoint")
+		}
+	}
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return exitUsage
+	}
+	if c.format != outputHuman && c.format != outputJSON && c.format != outputCSV {
+		fmt.Fprintln(stderr, errOutput)
+		return exitUsage
+	}
+	for name, v := range params {
+		if *v != "" {
+			c.query.Set(name, *v)
+		}
+	}
+	c.server = strings.TrimSuffix(c.server, "/")
+	c.http = &http.Client{Timeout: *timeout}
+	return command(c, fs.Args())
+}
+
+// calc calculate op of operands, or expression of eval
+func (c *client) calc(args []string) int {
+	if len(args) < 2 {
+		fmt.Fprintln(c.stderr, calcUsage)
+		return exitUsage
+	}
+	path := "/eval"
+	q := c.query
+	if args[0] == "eval" {
+		q.Set("expr", strings.Join(args[1:], " "))
+	} else {
+		op, ok := findOp(args[0])
+		if ok == false {
+			fmt.Fprintln(c.stderr, errUnknownOp(args[0]))
+			return exitUsage
+		}
+		path = op.Route
+		if q.Get("type") == typeRational {
+			path = "/" + typeRational + op.Route
+			q.Del("type")
+		}
+		if operands := args[1:]; len(operands) == 2 {
+			q.Set("x", operands[0])
+			q.Set("y", operands[1])
+		} else {
+			q["v"] = operands
+		}
+	}
+
+	status, body, err := c.do("GET", path+"?"+q.Encode(), nil)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUnavailable
+	}
+	result, err := decodeResult(body)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+	if c.format == outputCSV {
+		c.writeCSV(csvHeader, [][]string{csvRow(result)})
+	} else {
+		c.write(result, body)
+	}
+	if status >= 400 {
+		return exitError
+	}
+	return exitOK
+}
+
+// health print cache status
+func (c *client) health(args []string) int {
+	status, body, err := c.do("GET", "/health", nil)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUnavailable
+	}
+	result, err := decodeResult(body)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitError
+	}
+	switch c.format {
+	case outputJSON:
+		fmt.Fprintln(c.stdout, string(bytes.TrimSpace(body)))
+	case outputCSV:
+		c.writeCSV([]string{"cache", "hit", "size"}, [][]string{{resultField(result, "cache"), resultField(result, "hit"), resultField(result, "size")}})
+	default:
+		if status >= 400 {
+			c.write(result, body)
+			break
+		}
+		fmt.Fprintf(c.stdout, "cache %v, hit %v, size %v\n", resultField(result, "cache"), resultField(result, "hit"), resultField(result, "size"))
+	}
+	if status >= 400 || result["cache"] != "OK" {
+		return exitError
+	}
+	return exitOK
+}
+
+// batch calculate items of JSON lines from stdin, like {"op": "add", "x": 1, "y": 2}.
+// items are sent in batches of maxBatchSize, results are printed in order of lines.
+// line which is not JSON gets invalid_body error without being sent
+func (c *client) batch(args []string) int {
+	var lines [][]byte
+	scanner := bufio.NewScanner(c.stdin)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUsage
+	}
+
+	results := make([]map[string]interface{}, len(lines))
+	raws := make([][]byte, len(lines))
+	var items []json.RawMessage
+	var indexes []int
+	for i, line := range lines {
+		if json.Valid(line) == false {
+			raws[i], _ = json.Marshal(problemOf(errBatchItem, codeInvalidBody).body("", ""))
+			results[i], _ = decodeResult(raws[i])
+			continue
+		}
+		items = append(items, line)
+		indexes = append(indexes, i)
+	}
+	for start := 0; start < len(items); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		body, _ := json.Marshal(items[start:end])
+		status, resp, err := c.do("POST", "/batch", body)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			return exitUnavailable
+		}
+		if status >= 400 {
+			result, _ := decodeResult(resp)
+			c.write(result, resp)
+			return exitError
+		}
+		var batchResults []json.RawMessage
+		json.Unmarshal(resp, &batchResults)
+		for j, raw := range batchResults {
+			i := indexes[start+j]
+			raws[i] = raw
+			results[i], _ = decodeResult(raw)
+		}
+	}
+
+	code := exitOK
+	rows := [][]string{}
+	for i, result := range results {
+		if _, failed := result["code"]; failed {
+			code = exitError
+		}
+		switch c.format {
+		case outputCSV:
+			rows = append(rows, csvRow(result))
+		default:
+			c.write(result, raws[i])
+		}
+	}
+	if c.format == outputCSV {
+		c.writeCSV(csvHeader, rows)
+	}
+	return code
+}
+
+// do send request to server, retry if server can not be reached or responds 5xx or 429.
+// status and body of the last response are returned
+func (c *client) do(method, path string, body []byte) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		status, b, err := c.send(method, path, body)
+		retry := err != nil || status >= 500 || status == 429
+		if retry == false || attempt >= c.retries {
+			return status, b, err
+		}
+		Debug.Printf("retry %v %v after status %v, err: %v\n", method, path, status, err)
+		time.Sleep(retryBackoff << uint(attempt))
+	}
+}
+
+// send request to server, body is JSON if not nil
+func (c *client) send(method, path string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, c.server+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", mimeJSON)
+	if body != nil {
+		req.Header.Set("Content-Type", mimeJSON)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, b, err
+}
+
+// decodeResult decode response body, numbers are kept as is
+func decodeResult(body []byte) (map[string]interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var result map[string]interface{}
+	err := d.Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid response from server: %v", err)
+	}
+	return result, nil
+}
+
+// write print result in human format, or raw as a JSON line.
+// problem details are printed to stderr in human format
+func (c *client) write(result map[string]interface{}, raw []byte) {
+	if c.format == outputJSON {
+		fmt.Fprintln(c.stdout, string(bytes.TrimSpace(raw)))
+		return
+	}
+	if _, failed := result["code"]; failed {
+		msg := fmt.Sprintf("error: %v (%v", resultField(result, "detail"), resultField(result, "code"))
+		if f := resultField(result, "field"); f != "" {
+			msg += ", field " + f
+		}
+		fmt.Fprintln(c.stderr, msg+")")
+		return
+	}
+	answer := resultField(result, "answer")
+	if result["cached"] == true {
+		answer += " (cached)"
+	}
+	fmt.Fprintln(c.stdout, answer)
+}
+
+func (c *client) writeCSV(header []string, rows [][]string) {
+	w := csv.NewWriter(c.stdout)
+	w.Write(header)
+	w.WriteAll(rows)
+}
+
+// csvRow return row of result in csvHeader, operands are separated by space
+func csvRow(result map[string]interface{}) []string {
+	if _, failed := result["code"]; failed {
+		return []string{"", "", "", "", resultField(result, "code")}
+	}
+	operands := resultField(result, "expr")
+	if values, ok := result["values"].([]interface{}); ok {
+		s := make([]string, len(values))
+		for i, v := range values {
+			s[i] = fmt.Sprint(v)
+		}
+		operands = strings.Join(s, " ")
+	} else if _, ok := result["x"]; ok {
+		operands = resultField(result, "x") + " " + resultField(result, "y")
+	}
+	return []string{resultField(result, "action"), operands, resultField(result, "answer"), resultField(result, "cached"), ""}
+}
+
+// resultField return key of result as string, empty if missing
+func resultField(result map[string]interface{}, key string) string {
+	v, ok := result[key]
+	if ok == false || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	ts := httptest.NewServer(newRouter())
+	defer ts.Close()
+
+	cases := []struct {
+		name              string
+		args              []string
+		stdin             string
+		expCode           int
+		expOut, expStderr string
+		fCache            *fakeCacheClient
+	}{
+		{
+			name: "case calc", args: []string{"calc", "add", "2", "5"}, expCode: exitOK, expOut: "7\n",
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case cached", args: []string{"calc", "add", "5", "2"}, expCode: exitOK, expOut: "7 (cached)\n",
+			fCache: &fakeCacheClient{val: map[string]string{"add:2:5": "7"}},
+		},
+		{
+			name: "case values", args: []string{"calc", "--output", "csv", "subtract", "10", "-3", "2"}, expCode: exitOK,
+			expOut: "action,operands,answer,cached,error\nsubtract,10 -3 2,11,false,\n", fCache: NewFakeCache(),
+		},
+		{
+			name: "case json", args: []string{"calc", "--output", "json", "--mode", "trunc", "divide", "-7", "2"}, expCode: exitOK,
+			expOut: `{"action":"divide","answer":-3,"cached":false,"mode":"trunc","remainder":-1,"x":-7,"y":2}` + "\n", fCache: NewFakeCache(),
+		},
+		{
+			name: "case eval", args: []string{"calc", "eval", "(2+3)", "*", "4"}, expCode: exitOK, expOut: "20\n",
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case decimal", args: []string{"calc", "--type", "decimal", "--scale", "2", "div", "1", "3"}, expCode: exitOK, expOut: "0.33\n",
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case error", args: []string{"calc", "multiply", "2", "a"}, expCode: exitError,
+			expStderr: "error: " + errType.Error() + " (invalid_type, field y)\n", fCache: NewFakeCache(),
+		},
+		{
+			name: "case error csv", args: []string{"calc", "--output", "csv", "divide", "2", "0"}, expCode: exitError,
+			expOut: "action,operands,answer,cached,error\n,,,,divide_by_zero\n", fCache: NewFakeCache(),
+		},
+		{
+			name: "case unknown op", args: []string{"calc", "pow", "2", "5"}, expCode: exitUsage,
+			expStderr: errUnknownOp("pow").Error() + "\n", fCache: NewFakeCache(),
+		},
+		{
+			name: "case invalid output", args: []string{"health", "--output", "xml"}, expCode: exitUsage,
+			expStderr: errOutput.Error() + "\n", fCache: NewFakeCache(),
+		},
+		{
+			name: "case health", args: []string{"health"}, expCode: exitOK, expOut: "cache OK, hit 3, size 1\n",
+			fCache: &fakeCacheClient{val: map[string]string{"add:2:5": "7"}, hit: 3},
+		},
+		{
+			name: "case health csv", args: []string{"health", "--output=csv"}, expCode: exitOK, expOut: "cache,hit,size\nOK,0,0\n",
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case batch", args: []string{"batch"}, stdin: "{\"op\": \"add\", \"x\": 1, \"y\": 2}\n\nnot json\n{\"op\": \"div\", \"x\": 1, \"y\": 0}\n",
+			expCode: exitError, expOut: "3\n",
+			expStderr: "error: " + errBatchItem.Error() + " (invalid_body)\nerror: " + errDivideByZero.Error() + " (divide_by_zero, field y)\n",
+			fCache:    NewFakeCache(),
+		},
+		{
+			name: "case batch csv", args: []string{"batch", "-output", "csv"}, stdin: "{\"op\": \"add\", \"x\": 1, \"y\": 2}\n{\"op\": \"sub\", \"x\": 1, \"y\": 2}\n",
+			expCode: exitOK, expOut: "action,operands,answer,cached,error\nadd,1 2,3,false,\nsubtract,1 2,-1,false,\n", fCache: NewFakeCache(),
+		},
+	}
+	for _, c := range cases {
+		cache = c.fCache
+		var stdout, stderr bytes.Buffer
+		args := append([]string{c.args[0], "--server", ts.URL}, c.args[1:]...)
+		code := runClient(args, strings.NewReader(c.stdin), &stdout, &stderr)
+		if code != c.expCode || stdout.String() != c.expOut || stderr.String() != c.expStderr {
+			t.Errorf("error on: %v\ngot code, stdout and stderr:\n %v %q %q \nexp code, stdout and stderr\n %v %q %q \n",
+				c.name, code, stdout.String(), stderr.String(), c.expCode, c.expOut, c.expStderr)
+		}
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	setUpLogger(false)
+	backoff := retryBackoff
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = backoff }()
+
+	// server is unavailable for the first 2 requests
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"cache":"OK","hit":1,"size":2}`))
+	}))
+	defer ts.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := runClient([]string{"health", "--server", ts.URL, "--retries", "2"}, nil, &stdout, &stderr)
+	if code != exitOK || stdout.String() != "cache OK, hit 1, size 2\n" || requests != 3 {
+		t.Errorf("error on: case retry\ngot code, stdout and requests:\n %v %q %v \nexp code, stdout and requests\n %v %q %v \n",
+			code, stdout.String(), requests, exitOK, "cache OK, hit 1, size 2\n", 3)
+	}
+
+	// nothing is listening on a closed port
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := l.Addr().String()
+	l.Close()
+	code = runClient([]string{"health", "--server", "http://" + addr, "--retries", "1"}, nil, &stdout, &stderr)
+	if code != exitUnavailable {
+		t.Errorf("error on: case unavailable\ngot code:\n %v \nexp code\n %v \n", code, exitUnavailable)
+	}
+}
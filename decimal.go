@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	typeInteger = "integer"
+	typeDecimal = "decimal"
+
+	maxDecimalScale = 100
+)
+
+// rounding modes of decimal, same naming as java.math.RoundingMode
+const (
+	roundUp       = "up"
+	roundDown     = "down"
+	roundCeiling  = "ceiling"
+	roundFloor    = "floor"
+	roundHalfUp   = "half-up"
+	roundHalfDown = "half-down"
+	roundHalfEven = "half-even"
+)
+
+var roundingModes = []string{roundUp, roundDown, roundCeiling, roundFloor, roundHalfUp, roundHalfDown, roundHalfEven}
+
+var errNumType = fmt.Errorf("Unsupported type. %v or %v only", typeInteger, typeDecimal)
+var errScale = fmt.Errorf("Invalid scale. Integer between 0 and %d only", maxDecimalScale)
+var errRounding = fmt.Errorf("Unsupported rounding mode. One of %v only", strings.Join(roundingModes, ", "))
+
+// defaultScale and defaultRounding will be used if not set in query string.
+// can be changed via --scale and --rounding flag
+var (
+	defaultScale    = 10
+	defaultRounding = roundHalfEven
+)
+
+var decimalRegex = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+// decimal is a fixed point number: unscaled * 10^-scale
+// for example, 1.50 is {unscaled: 150, scale: 2}
+type decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// parseDecimal parse s in format of `[+-]digits[.digits]`.
+// exponent (1e3) is not supported.
+// returned decimal is normalized, trailing zeros are removed
+func parseDecimal(s string) (decimal, bool) {
+	if decimalRegex.MatchString(s) == false {
+		return decimal{}, false
+	}
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	unscaled, ok := new(big.Int).SetString(s, 10)
+	if ok == false {
+		return decimal{}, false
+	}
+	return decimal{unscaled: unscaled, scale: scale}.normalize(), true
+}
+
+// normalize removes trailing zeros after decimal point
+// so that 1.50 and 1.5 have same representation
+func (d decimal) normalize() decimal {
+	u := new(big.Int).Set(d.unscaled)
+	scale := d.scale
+	ten := big.NewInt(10)
+	q, r := new(big.Int), new(big.Int)
+	for scale > 0 {
+		q.QuoRem(u, ten, r)
+		if r.Sign() != 0 {
+			break
+		}
+		u.Set(q)
+		scale--
+	}
+	return decimal{unscaled: u, scale: scale}
+}
+
+// String return canonical string of d, like `-1.5`, `0.25` or `3`
+func (d decimal) String() string {
+	d = d.normalize()
+	digits := new(big.Int).Abs(d.unscaled).String()
+	sign := ""
+	if d.unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+	point := len(digits) - d.scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Cmp compares d and e, return -1, 0 or 1 like big.Int.Cmp
+func (d decimal) Cmp(e decimal) int {
+	return d.rat().Cmp(e.rat())
+}
+
+// rat return d as big.Rat, conversion is lossless
+func (d decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled, pow10(d.scale))
+}
+
+// ratToDecimal round r to scale digits after decimal point with mode
+func ratToDecimal(r *big.Rat, scale int, mode string) decimal {
+	n := new(big.Int).Mul(r.Num(), pow10(scale))
+	return decimal{unscaled: roundQuo(n, r.Denom(), mode), scale: scale}.normalize()
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundQuo return n / d rounded with mode.
+// d should not be zero
+func roundQuo(n, d *big.Int, mode string) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	// sign of the exact quotient
+	sign := int64(n.Sign() * d.Sign())
+	// compare 2 * |r| with |d| to find out if remainder is half way or more
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmpHalf := half.Cmp(new(big.Int).Abs(d))
+
+	awayFromZero := false
+	switch mode {
+	case roundUp:
+		awayFromZero = true
+	case roundDown:
+	case roundCeiling:
+		awayFromZero = sign > 0
+	case roundFloor:
+		awayFromZero = sign < 0
+	case roundHalfUp:
+		awayFromZero = cmpHalf >= 0
+	case roundHalfDown:
+		awayFromZero = cmpHalf > 0
+	case roundHalfEven:
+		awayFromZero = cmpHalf > 0 || (cmpHalf == 0 && q.Bit(0) == 1)
+	default:
+		panic(fmt.Sprintf("invalid rounding mode %v", mode))
+	}
+	if awayFromZero {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+// calculateDecimal do the calculation with Rat of the registered operation,
+// exact result is rounded to scale digits.
+// caller should make sure Rat is not nil
+func calculateDecimal(f string, scale int, mode string, values ...decimal) (decimal, error) {
+	args := make([]*big.Rat, len(values))
+	for i, v := range values {
+		args[i] = v.rat()
+	}
+	result, err := getOp(f).Rat(args)
+	if err != nil {
+		return decimal{}, err
+	}
+	return ratToDecimal(result, scale, mode), nil
+}
+
+// genDecimalCacheKey generate key of cache in format of `decimal:scale:rounding:func:v1:v2:...`.
+// values are canonical strings so that 1.50 and 1.5 share the same key.
+// same as genCacheKey, values will be sorted for commutative operation.
+func genDecimalCacheKey(f string, scale int, mode string, values ...decimal) string {
+	if getOp(f).Commutative {
+		values = append([]decimal(nil), values...)
+		sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	}
+	keys := make([]interface{}, len(values))
+	for i, v := range values {
+		keys[i] = v
+	}
+	prefix := fmt.Sprintf("%v:%v:%v:%v", typeDecimal, scale, mode, f)
+	return genUnSortedCacheKey(prefix, keys...)
+}
+
+// getDecimalResult is the decimal version of getResult.
+// value is cached as canonical decimal string
+func getDecimalResult(ttl time.Duration, f string, scale int, mode string, values ...decimal) (decimal, bool, error) {
+	cacheKey := genDecimalCacheKey(f, scale, mode, values...)
+	e := newCalcEvent(f, typeDecimal, len(values), func(i int) interface{} { return values[i] })
+	val, cached, err := cachedResult(cacheKey, ttl, e, func() (string, error) {
+		result, err := calculateDecimal(f, scale, mode, values...)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	})
+	if err != nil {
+		return decimal{}, false, err
+	}
+	result, ok := parseDecimal(val)
+	if ok == false {
+		Warning.Printf("invalid cached value %v of key %v\n", val, cacheKey)
+		result, err = calculateDecimal(f, scale, mode, values...)
+		return result, false, err
+	}
+	return result, cached, nil
+}
+
+// validRounding checks if mode is one of the supported rounding modes
+func validRounding(mode string) bool {
+	for _, m := range roundingModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// validScale checks if scale is within [0, maxDecimalScale]
+func validScale(scale int) bool {
+	return scale >= 0 && scale <= maxDecimalScale
+}
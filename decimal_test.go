@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func mustDecimal(s string) decimal {
+	d, ok := parseDecimal(s)
+	if ok == false {
+		panic("invalid decimal " + s)
+	}
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	cases := []struct {
+		name, s, expected string
+		expBool           bool
+	}{
+		{name: "case int", s: "3", expected: "3", expBool: true},
+		{name: "case trailing zeros", s: "1.50", expected: "1.5", expBool: true},
+		{name: "case trailing zeros 2", s: "100.000", expected: "100", expBool: true},
+		{name: "case leading dot", s: "-.25", expected: "-0.25", expBool: true},
+		{name: "case trailing dot", s: "+5.", expected: "5", expBool: true},
+		{name: "case small", s: "0.0005", expected: "0.0005", expBool: true},
+		{name: "case negative zero", s: "-0.00", expected: "0", expBool: true},
+		{name: "case empty", s: "", expBool: false},
+		{name: "case dot", s: ".", expBool: false},
+		{name: "case exponent", s: "1e3", expBool: false},
+		{name: "case letters", s: "abc", expBool: false},
+		{name: "case two dots", s: "1.2.3", expBool: false},
+	}
+	for _, c := range cases {
+		got, gotBool := parseDecimal(c.s)
+		if gotBool != c.expBool {
+			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
+		}
+		if gotBool && got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestRoundQuo(t *testing.T) {
+	cases := []struct {
+		name, mode string
+		n, d       int64
+		expected   int64
+	}{
+		{name: "case up", mode: roundUp, n: 5, d: 2, expected: 3},
+		{name: "case up negative", mode: roundUp, n: -5, d: 2, expected: -3},
+		{name: "case down", mode: roundDown, n: -5, d: 2, expected: -2},
+		{name: "case ceiling", mode: roundCeiling, n: 5, d: 2, expected: 3},
+		{name: "case ceiling negative", mode: roundCeiling, n: -5, d: 2, expected: -2},
+		{name: "case floor", mode: roundFloor, n: 5, d: 2, expected: 2},
+		{name: "case floor negative", mode: roundFloor, n: 5, d: -2, expected: -3},
+		{name: "case half-up", mode: roundHalfUp, n: 5, d: 2, expected: 3},
+		{name: "case half-up negative", mode: roundHalfUp, n: -5, d: 2, expected: -3},
+		{name: "case half-down", mode: roundHalfDown, n: 5, d: 2, expected: 2},
+		{name: "case half-down above half", mode: roundHalfDown, n: 8, d: 3, expected: 3},
+		{name: "case half-even down", mode: roundHalfEven, n: 5, d: 2, expected: 2},
+		{name: "case half-even up", mode: roundHalfEven, n: 7, d: 2, expected: 4},
+		{name: "case half-even negative", mode: roundHalfEven, n: -7, d: 2, expected: -4},
+		{name: "case exact", mode: roundUp, n: 6, d: 2, expected: 3},
+	}
+	for _, c := range cases {
+		got := roundQuo(big.NewInt(c.n), big.NewInt(c.d), c.mode)
+		if got.Int64() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestCalculateDecimal(t *testing.T) {
+	cases := []struct {
+		name, f, v1, v2 string
+		scale           int
+		mode, expected  string
+	}{
+		{name: "case add", f: "add", v1: "1.5", v2: "0.25", scale: 10, mode: roundHalfEven, expected: "1.75"},
+		{name: "case sub", f: "sub", v1: "0.1", v2: "0.3", scale: 10, mode: roundHalfEven, expected: "-0.2"},
+		{name: "case mul", f: "mul", v1: "1.5", v2: "1.5", scale: 10, mode: roundHalfEven, expected: "2.25"},
+		{name: "case mul rounded", f: "mul", v1: "1.5", v2: "1.5", scale: 1, mode: roundHalfEven, expected: "2.2"},
+		{name: "case mul rounded half-up", f: "mul", v1: "1.5", v2: "1.5", scale: 1, mode: roundHalfUp, expected: "2.3"},
+		{name: "case div", f: "div", v1: "1", v2: "3", scale: 4, mode: roundHalfEven, expected: "0.3333"},
+		{name: "case div 2", f: "div", v1: "2", v2: "3", scale: 4, mode: roundHalfEven, expected: "0.6667"},
+		{name: "case div floor", f: "div", v1: "-2", v2: "3", scale: 2, mode: roundFloor, expected: "-0.67"},
+		{name: "case div scale 0", f: "div", v1: "7", v2: "2", scale: 0, mode: roundHalfEven, expected: "4"},
+		{name: "case div decimals", f: "div", v1: "0.75", v2: "0.5", scale: 10, mode: roundHalfEven, expected: "1.5"},
+		{name: "case div exact", f: "div", v1: "10", v2: "0.25", scale: 0, mode: roundDown, expected: "40"},
+	}
+	for _, c := range cases {
+		got, _ := calculateDecimal(c.f, c.scale, c.mode, mustDecimal(c.v1), mustDecimal(c.v2))
+		if got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestGenDecimalCacheKey(t *testing.T) {
+	cases := []struct {
+		name, f, v1, v2, expected string
+	}{
+		{name: "case normalized", f: "add", v1: "1.50", v2: "2", expected: "decimal:2:half-even:add:1.5:2"},
+		{name: "case sorted", f: "mul", v1: "2.0", v2: "1.5", expected: "decimal:2:half-even:mul:1.5:2"},
+		{name: "case not sorted", f: "div", v1: "2.0", v2: "1.5", expected: "decimal:2:half-even:div:2:1.5"},
+	}
+	for _, c := range cases {
+		got := genDecimalCacheKey(c.f, 2, roundHalfEven, mustDecimal(c.v1), mustDecimal(c.v2))
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestGetDecimalResult(t *testing.T) {
+	cases := []struct {
+		name, f, x, y, expected string
+		expBool                 bool
+		fCache                  *fakeCacheClient
+	}{
+		{
+			name: "case 1", f: "add", x: "1.50", y: "2", expected: "3.5",
+			fCache: NewFakeCache(), expBool: false,
+		},
+		{
+			name: "case 2", f: "add", x: "2", y: "1.5", expected: "3.5",
+			fCache: &fakeCacheClient{val: map[string]string{"decimal:10:half-even:add:1.5:2": "3.5"}}, expBool: true,
+		},
+	}
+	for _, c := range cases {
+		cache = c.fCache
+
+		got, gotBool, _ := getDecimalResult(time.Minute, c.f, 10, roundHalfEven, mustDecimal(c.x), mustDecimal(c.y))
+		if got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+		if gotBool != c.expBool {
+			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errDivMode = fmt.Errorf("Unsupported division mode. One of %v only", strings.Join(ops.DivModes, ", "))
+
+// defaultDivMode is used if mode is not set in query string.
+// division is documented as floor function
+var defaultDivMode = ops.DivFloor
+
+// validDivMode checks if mode is one of the supported division modes
+func validDivMode(mode string) bool {
+	for _, m := range ops.DivModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// genDivCacheKey generate key of cache in format of `div:mode:v1:v2:...`
+// so that different modes never collide
+func genDivCacheKey(mode string, values ...int64) string {
+	keys := make([]interface{}, len(values))
+	for i, v := range values {
+		keys[i] = v
+	}
+	return genUnSortedCacheKey("div:"+mode, keys...)
+}
+
+// divideWithMode fold values from left to right with ops.DivideWithMode
+// and return the quotient
+func divideWithMode(mode string, values ...int64) (int64, error) {
+	q := values[0]
+	for _, v := range values[1:] {
+		var err error
+		q, _, err = ops.DivideWithMode(q, v, mode)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return q, nil
+}
+
+// getDivResult is the getResult of division with rounding mode.
+// only quotient is cached, remainder is derived from it.
+// remainder is only returned for 2 values, it is 0 otherwise
+func getDivResult(ttl time.Duration, mode string, values ...int64) (int64, int64, bool, error) {
+	cacheKey := genDivCacheKey(mode, values...)
+	e := newCalcEvent("div", typeInteger, len(values), func(i int) interface{} { return values[i] })
+	val, cached, err := cachedResult(cacheKey, ttl, e, func() (string, error) {
+		q, err := divideWithMode(mode, values...)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(q, 10), nil
+	})
+	if err != nil {
+		return 0, 0, false, err
+	}
+	q, err := stringToInt(val)
+	if err != nil {
+		Warning.Printf("invalid cached value %v of key %v\n", val, cacheKey)
+		cached = false
+		q, err = divideWithMode(mode, values...)
+		if err != nil {
+			return 0, 0, false, err
+		}
+	}
+	if len(values) != 2 {
+		return q, 0, cached, nil
+	}
+	// wrapping arithmetic, see ops.DivideWithMode
+	return q, values[0] - q*values[1], cached, nil
+}
+
+// bigDivideWithMode is the big.Int version of divideWithMode
+func bigDivideWithMode(mode string, values ...*big.Int) *big.Int {
+	q := values[0]
+	for _, v := range values[1:] {
+		q, _ = ops.BigDivideWithMode(q, v, mode)
+	}
+	return q
+}
+
+// genBigDivCacheKey is the big.Int version of genDivCacheKey, `big:div:mode:v1:v2:...`
+func genBigDivCacheKey(mode string, values ...*big.Int) string {
+	keys := make([]interface{}, len(values))
+	for i, v := range values {
+		keys[i] = v
+	}
+	return genUnSortedCacheKey(precisionBig+":div:"+mode, keys...)
+}
+
+// getBigDivResult is the big.Int version of getDivResult
+func getBigDivResult(ttl time.Duration, mode string, values ...*big.Int) (*big.Int, *big.Int, bool) {
+	cacheKey := genBigDivCacheKey(mode, values...)
+	e := newCalcEvent("div", precisionBig, len(values), func(i int) interface{} { return values[i] })
+	val, cached, _ := cachedResult(cacheKey, ttl, e, func() (string, error) {
+		return bigDivideWithMode(mode, values...).String(), nil
+	})
+	q, ok := new(big.Int).SetString(val, 10)
+	if ok == false {
+		Warning.Printf("invalid cached value %v of key %v\n", val, cacheKey)
+		q, cached = bigDivideWithMode(mode, values...), false
+	}
+	if len(values) != 2 {
+		return q, new(big.Int), cached
+	}
+	r := new(big.Int).Sub(values[0], new(big.Int).Mul(q, values[1]))
+	return q, r, cached
+}
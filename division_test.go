@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/ThisisYang/teltechcc/ops"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestGetDivResult(t *testing.T) {
+	cases := []struct {
+		name, mode       string
+		x, y, expQ, expR int64
+		expBool          bool
+		fCache           *fakeCacheClient
+	}{
+		{
+			name: "case uncached", mode: ops.DivFloor, x: -7, y: 2, expQ: -4, expR: 1,
+			fCache: NewFakeCache(), expBool: false,
+		},
+		{
+			name: "case cached", mode: ops.DivFloor, x: -7, y: 2, expQ: -4, expR: 1,
+			fCache: &fakeCacheClient{val: map[string]string{"div:floor:-7:2": "-4"}}, expBool: true,
+		},
+		{
+			name: "case other mode cached", mode: ops.DivTrunc, x: -7, y: 2, expQ: -3, expR: -1,
+			fCache: &fakeCacheClient{val: map[string]string{"div:floor:-7:2": "-4"}}, expBool: false,
+		},
+	}
+	for _, c := range cases {
+		cache = c.fCache
+
+		gotQ, gotR, gotBool, _ := getDivResult(time.Minute, c.mode, c.x, c.y)
+		if gotQ != c.expQ || gotR != c.expR {
+			t.Errorf("error on: %v\ngot q, r:\n %v %v \nexp q, r\n %v %v \n", c.name, gotQ, gotR, c.expQ, c.expR)
+		}
+		if gotBool != c.expBool {
+			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
+		}
+	}
+}
+
+// TestDivSharedKey checks getResult of div shares cache with getDivResult of defaultDivMode
+func TestDivSharedKey(t *testing.T) {
+	cache = NewFakeCache()
+	getResult(time.Minute, "div", -7, 2)
+	if q, _, cached, _ := getDivResult(time.Minute, defaultDivMode, -7, 2); q != -4 || cached == false {
+		t.Errorf("error on: getResult then getDivResult\ngot q and cached:\n %v %v \nexp q and cached\n %v %v \n", q, cached, -4, true)
+	}
+	getBigDivResult(time.Minute, defaultDivMode, big.NewInt(-9), big.NewInt(2))
+	if q, cached, _ := getBigResult(time.Minute, "div", big.NewInt(-9), big.NewInt(2)); q.Int64() != -5 || cached == false {
+		t.Errorf("error on: getBigDivResult then getBigResult\ngot q and cached:\n %v %v \nexp q and cached\n %v %v \n", q, cached, -5, true)
+	}
+}
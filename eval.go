@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ThisisYang/teltechcc/expr"
+	"github.com/gin-gonic/gin"
+	"math/big"
+	"time"
+)
+
+// maxExprLength is the max length of expression accepted by /eval
+const maxExprLength = 1024
+
+var errMissExpr = fmt.Errorf("expr is not provided")
+var errExprLength = fmt.Errorf("expr is too long, max %d characters", maxExprLength)
+
+// evalOps maps operator of expression to name of registered operation
+var evalOps = map[string]string{
+	"+": "add",
+	"-": "sub",
+	"*": "mul",
+	"/": "div",
+	"%": "mod",
+}
+
+// evalStats counts binary nodes evaluated and the ones served from cache
+type evalStats struct {
+	nodes, hit int
+}
+
+// evaluate walks the AST with the same semantics as calculate.
+// every binary node goes through getResult,
+// so that sub-expression like 2+3 shares cache with /add?x=2&y=3 and other expressions.
+// ttl return TTL of each op, see requestTTL.
+// error is *expr.Error pointing at the offending literal or operator
+func evaluate(node expr.Node, ttl func(name string) time.Duration, stats *evalStats) (int64, error) {
+	switch n := node.(type) {
+	case *expr.Number:
+		v, err := stringToInt(n.Value)
+		if err != nil {
+			return 0, &expr.Error{Pos: n.Pos(), Err: errType}
+		}
+		return v, nil
+	case *expr.Neg:
+		x, err := evaluate(n.X, ttl, stats)
+		if err != nil {
+			return 0, err
+		}
+		// -x is 0 - x, overflow if x is MinInt64
+		result, err := calculate("sub", 0, x)
+		if err != nil {
+			return 0, &expr.Error{Pos: n.Pos(), Err: err}
+		}
+		return result, nil
+	case *expr.Binary:
+		x, err := evaluate(n.X, ttl, stats)
+		if err != nil {
+			return 0, err
+		}
+		y, err := evaluate(n.Y, ttl, stats)
+		if err != nil {
+			return 0, err
+		}
+		op := getOp(evalOps[n.Op])
+		err = ruleValidation(op, big.NewRat(x, 1), big.NewRat(y, 1))
+		if err != nil {
+			return 0, &expr.Error{Pos: n.Pos(), Err: err}
+		}
+		result, cached, err := getResult(ttl(op.Name), op.Name, x, y)
+		if err != nil {
+			return 0, &expr.Error{Pos: n.Pos(), Err: err}
+		}
+		stats.nodes++
+		if cached {
+			stats.hit++
+		}
+		return result, nil
+	}
+	panic(fmt.Sprintf("unknown node %T", node))
+}
+
+// eval endpoint, evaluate expr in query string, like (2+3)*4-10/3.
+// operators are + - * / and %, division is floor function, % is mod.
+// nodes is the number of binary sub-expressions, hit is the number of them served from cache
+func eval(ctx *gin.Context) {
+	s := ctx.Query("expr")
+	if s == "" {
+		respondError(ctx, &fieldError{field: "expr", err: errMissExpr})
+		return
+	}
+	if len(s) > maxExprLength {
+		respondError(ctx, &fieldError{field: "expr", value: s, err: errExprLength})
+		return
+	}
+	tree, err := expr.Parse(s)
+	if err != nil {
+		evalError(ctx, s, err)
+		return
+	}
+	ttl, err := requestTTL(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	Debug.Println("recieved:", tree)
+	stats := &evalStats{}
+	result, err := evaluate(tree, ttl, stats)
+	if err != nil {
+		evalError(ctx, s, err)
+		return
+	}
+	respond(ctx, 200, gin.H{"action": "eval", "expr": s, "answer": result, "nodes": stats.nodes, "hit": stats.hit})
+}
+
+// evalError respond error of parsing or evaluation of s with position of the offending token.
+// same as other endpoints, calculation error like overflow is 422, others are 400.
+// syntax error is invalid_expression
+func evalError(ctx *gin.Context, s string, err error) {
+	p := problemOf(&fieldError{field: "expr", value: s, err: err}, codeInvalidExpr)
+	if e, ok := err.(*expr.Error); ok {
+		p.ext = gin.H{"pos": e.Pos}
+	}
+	respondProblem(ctx, p)
+}
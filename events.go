@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"io"
+	"sync"
+	"time"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber of /events.
+// events are dropped once the buffer is full, so that slow subscriber never blocks handlers
+var eventBufferSize = 256
+
+// statsInterval is the interval of cache stats event
+var statsInterval = 5 * time.Second
+
+// calcEvent is a calculation streamed to /events.
+// operands are strings in the same format as cache key, result is empty if err
+type calcEvent struct {
+	Op       string   `json:"op"`
+	Type     string   `json:"type"`
+	Operands []string `json:"operands"`
+	Result   string   `json:"result,omitempty"`
+	Cached   bool     `json:"cached"`
+	Error    string   `json:"error,omitempty"`
+	// Latency is the time of cache lookup and calculation in microseconds
+	Latency int64 `json:"latency_us"`
+}
+
+// newCalcEvent return event of n operands of op in numType, operand i is value(i)
+func newCalcEvent(op, numType string, n int, value func(i int) interface{}) calcEvent {
+	operands := make([]string, n)
+	for i := range operands {
+		operands[i] = fmt.Sprint(value(i))
+	}
+	return calcEvent{Op: op, Type: numType, Operands: operands}
+}
+
+// publishCalc set result of e and publish it to subscribers of /events.
+// start is the time calculation started
+func publishCalc(e calcEvent, start time.Time, result string, cached bool, err error) {
+	if broker.idle() {
+		return
+	}
+	e.Result, e.Cached, e.Latency = result, cached, time.Since(start).Microseconds()
+	if err != nil {
+		e.Result, e.Error = "", errorCode(err, codeCalculation)
+	}
+	broker.publish(sse.Event{Event: "calc", Data: e})
+}
+
+// broker fan out events to subscribers of /events, they are closed on shutdown of the HTTP server, see newServer
+var broker = newEventBroker()
+
+type eventBroker struct {
+	mutex       sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+// subscriber get events from events channel until closed is closed.
+// dropped is the number of events dropped since last report
+type subscriber struct {
+	events  chan sse.Event
+	closed  chan struct{}
+	mutex   sync.Mutex
+	dropped int
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[*subscriber]struct{})}
+}
+
+// subscribe return a subscriber buffering up to size events
+func (b *eventBroker) subscribe(size int) *subscriber {
+	s := &subscriber{events: make(chan sse.Event, size), closed: make(chan struct{})}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+func (b *eventBroker) unsubscribe(s *subscriber) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.subscribers, s)
+}
+
+// idle checks if there is no subscriber, so that events are not built for nothing
+func (b *eventBroker) idle() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.subscribers) == 0
+}
+
+// publish send e to every subscriber without blocking,
+// e is dropped for subscriber whose buffer is full
+func (b *eventBroker) publish(e sse.Event) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for s := range b.subscribers {
+		select {
+		case s.events <- e:
+		default:
+			s.mutex.Lock()
+			s.dropped++
+			s.mutex.Unlock()
+		}
+	}
+}
+
+// closeAll end streams of all subscribers
+func (b *eventBroker) closeAll() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for s := range b.subscribers {
+		close(s.closed)
+		delete(b.subscribers, s)
+	}
+}
+
+// takeDropped return number of dropped events and reset it
+func (s *subscriber) takeDropped() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n := s.dropped
+	s.dropped = 0
+	return n
+}
+
+// events endpoint, Server-Sent Events of every calculation and periodic cache stats.
+// `calc` is calcEvent, `stats` is the same as /health,
+// `dropped` reports number of events dropped since last report before the next event
+func events(ctx *gin.Context) {
+	s := broker.subscribe(eventBufferSize)
+	defer broker.unsubscribe(s)
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	// stats are sent at once, so that subscriber gets headers without waiting for a calculation
+	ctx.Render(200, sse.Event{Event: "stats", Data: healthStatus()})
+	ctx.Writer.Flush()
+	ctx.Stream(func(w io.Writer) bool {
+		var e sse.Event
+		select {
+		case e = <-s.events:
+		case <-ticker.C:
+			e = sse.Event{Event: "stats", Data: healthStatus()}
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-s.closed:
+			return false
+		}
+		if n := s.takeDropped(); n > 0 {
+			ctx.Render(-1, sse.Event{Event: "dropped", Data: gin.H{"dropped": n}})
+		}
+		ctx.Render(-1, e)
+		return true
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent read the next event of stream, data is returned as is
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	var name, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, data
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimPrefix(line, "data:")
+		}
+	}
+}
+
+// subscribeEvents open /events of ts, stream is closed by cancel
+func subscribeEvents(t *testing.T, addr string) (*bufio.Reader, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://"+addr+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != sse.ContentType {
+		t.Fatalf("error on: subscribe\ngot status and content type:\n %v %v \n", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body), cancel
+}
+
+func TestEventBroker(t *testing.T) {
+	b := newEventBroker()
+	s := b.subscribe(2)
+	for i := 0; i < 5; i++ {
+		b.publish(sse.Event{Event: "calc", Data: i})
+	}
+	if len(s.events) != 2 {
+		t.Errorf("error on: case buffer\ngot buffered:\n %v \nexp buffered\n %v \n", len(s.events), 2)
+	}
+	if n := s.takeDropped(); n != 3 {
+		t.Errorf("error on: case dropped\ngot dropped:\n %v \nexp dropped\n %v \n", n, 3)
+	}
+	if n := s.takeDropped(); n != 0 {
+		t.Errorf("error on: case dropped reset\ngot dropped:\n %v \nexp dropped\n %v \n", n, 0)
+	}
+	b.unsubscribe(s)
+	if b.idle() == false {
+		t.Errorf("error on: case unsubscribe\nbroker is not idle")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	cache = NewFakeCache()
+	interval := statsInterval
+	statsInterval = time.Hour
+	defer func() { statsInterval = interval }()
+	ts := httptest.NewServer(newRouter())
+	defer ts.Close()
+	r, cancel := subscribeEvents(t, ts.Listener.Addr().String())
+	defer cancel()
+
+	name, data := readEvent(t, r)
+	exp, _ := json.Marshal(gin.H{"cache": "OK", "hit": 0, "size": 0})
+	if name != "stats" || data != string(exp) {
+		t.Errorf("error on: case stats\ngot event:\n %v %v \nexp event\n %v %v \n", name, data, "stats", string(exp))
+	}
+
+	cases := []struct {
+		name, path string
+		exp        calcEvent
+	}{
+		{name: "case miss", path: "/add?x=2&y=5", exp: calcEvent{Op: "add", Type: typeInteger, Operands: []string{"2", "5"}, Result: "7"}},
+		{name: "case hit", path: "/v2/add?x=5&y=2", exp: calcEvent{Op: "add", Type: typeInteger, Operands: []string{"5", "2"}, Result: "7", Cached: true}},
+		{name: "case error", path: "/multiply?x=9223372036854775807&y=2", exp: calcEvent{Op: "mul", Type: typeInteger, Operands: []string{"9223372036854775807", "2"}, Error: codeOverflow}},
+		{name: "case decimal", path: "/divide?x=1&y=3&type=decimal&scale=2", exp: calcEvent{Op: "div", Type: typeDecimal, Operands: []string{"1", "3"}, Result: "0.33"}},
+		{name: "case batch", path: "", exp: calcEvent{Op: "sub", Type: typeInteger, Operands: []string{"9", "4"}, Result: "5"}},
+	}
+	for _, c := range cases {
+		var err error
+		if c.path == "" {
+			_, err = http.Post(ts.URL+"/batch", mimeJSON, strings.NewReader(`[{"op": "sub", "x": 9, "y": 4}]`))
+		} else {
+			_, err = http.Get(ts.URL + c.path)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, data := readEvent(t, r)
+		var got calcEvent
+		json.Unmarshal([]byte(data), &got)
+		got.Latency = 0
+		g, _ := json.Marshal(got)
+		e, _ := json.Marshal(c.exp)
+		if name != "calc" || string(g) != string(e) {
+			t.Errorf("error on: %v\ngot event:\n %v %v \nexp event\n %v %v \n", c.name, name, string(g), "calc", string(e))
+		}
+	}
+}
+
+func TestEventsDropped(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	cache = NewFakeCache()
+	ts := httptest.NewServer(newRouter())
+	defer ts.Close()
+	r, cancel := subscribeEvents(t, ts.Listener.Addr().String())
+	defer cancel()
+	readEvent(t, r)
+
+	// as if 3 events were dropped by a full buffer
+	broker.mutex.RLock()
+	for s := range broker.subscribers {
+		s.mutex.Lock()
+		s.dropped = 3
+		s.mutex.Unlock()
+	}
+	broker.mutex.RUnlock()
+	broker.publish(sse.Event{Event: "calc", Data: gin.H{"op": "add"}})
+
+	exp := []struct{ name, data string }{{"dropped", `{"dropped":3}`}, {"calc", `{"op":"add"}`}}
+	for _, e := range exp {
+		name, data := readEvent(t, r)
+		if name != e.name || data != e.data {
+			t.Errorf("error on: case dropped\ngot event:\n %v %v \nexp event\n %v %v \n", name, data, e.name, e.data)
+		}
+	}
+}
+
+func TestEventsShutdown(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	cache = NewFakeCache()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newServer("127.0.0.1", 0)
+	go s.Serve(l)
+	r, cancel := subscribeEvents(t, l.Addr().String())
+	defer cancel()
+	readEvent(t, r)
+
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Errorf("error on: case shutdown\ngot err:\n %v \nexp err\n %v \n", err, nil)
+	}
+}
@@ -0,0 +1,71 @@
+package expr
+
+import (
+	"fmt"
+)
+
+// Node is a node of the abstract syntax tree.
+// Pos is the byte offset in expression, used to report error
+type Node interface {
+	Pos() int
+	String() string
+}
+
+// Number is an integer literal.
+// Value is kept as text, so that evaluator decides how to parse it,
+// negative literal like -3 has sign in Value
+type Number struct {
+	Value    string
+	Position int
+}
+
+// Pos return offset of the literal
+func (n *Number) Pos() int { return n.Position }
+
+func (n *Number) String() string { return n.Value }
+
+// Binary is an infix operation like `x + y`.
+// Position is the offset of the operator,
+// so that error like divide by zero points at the operator
+type Binary struct {
+	Op       string
+	X, Y     Node
+	Position int
+}
+
+// Pos return offset of the operator
+func (b *Binary) Pos() int { return b.Position }
+
+// String return fully parenthesized expression, like `((2 + 3) * 4)`
+func (b *Binary) String() string {
+	return fmt.Sprintf("(%v %v %v)", b.X, b.Op, b.Y)
+}
+
+// Neg is unary minus of an expression which is not a literal, like `-(2 + 3)`
+type Neg struct {
+	X        Node
+	Position int
+}
+
+// Pos return offset of the minus sign
+func (n *Neg) Pos() int { return n.Position }
+
+func (n *Neg) String() string {
+	return fmt.Sprintf("(-%v)", n.X)
+}
+
+// Error is returned by Parse and evaluator.
+// Pos is the byte offset of the offending token, starts from 0
+type Error struct {
+	Pos int
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v at position %d", e.Err, e.Pos)
+}
+
+// Unwrap return Err, so that errors.Is works for the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,175 @@
+// Package expr parses arithmetic expression like `(2+3)*4-10/3` into AST.
+//
+// Grammar, from lowest to highest precedence:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/" | "%") unary }
+//	unary   = "-" unary | "+" unary | primary
+//	primary = number | "(" expr ")"
+//
+// All binary operators are left associative: 10-3-2 is (10-3)-2.
+package expr
+
+import (
+	"fmt"
+)
+
+// MaxDepth is the max nesting level of parentheses and unary operators,
+// so that a malicious expression won't exhaust the stack
+const MaxDepth = 100
+
+var errEmpty = fmt.Errorf("empty expression")
+var errDepth = fmt.Errorf("expression is nested too deep, max %d levels", MaxDepth)
+
+type parser struct {
+	tokens []token
+	i      int
+	depth  int
+}
+
+// Parse tokenize and parse s into AST.
+// *Error is returned if s is not a valid expression
+func Parse(s string) (Node, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == tokenEOF {
+		return nil, &Error{Pos: 0, Err: errEmpty}
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, unexpected(t)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// expr = term { ("+" | "-") term }
+func (p *parser) expr() (Node, error) {
+	return p.binary(p.term, "+", "-")
+}
+
+// term = unary { ("*" | "/" | "%") unary }
+func (p *parser) term() (Node, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+// binary parse left associative operations of ops,
+// operands are parsed by operand which has higher precedence
+func (p *parser) binary(operand func() (Node, error), ops ...string) (Node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenOperator || contains(ops, t.text) == false {
+			return x, nil
+		}
+		p.next()
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: t.text, X: x, Y: y, Position: t.pos}
+	}
+}
+
+// unary = "-" unary | "+" unary | primary
+// minus sign followed by number directly is folded into the literal,
+// so that -9223372036854775808 is a valid int64
+func (p *parser) unary() (Node, error) {
+	t := p.peek()
+	if t.kind != tokenOperator || (t.text != "-" && t.text != "+") {
+		return p.primary()
+	}
+	p.next()
+	if err := p.enter(t); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if n := p.peek(); n.kind == tokenNumber {
+		p.next()
+		value := n.text
+		if t.text == "-" {
+			value = "-" + value
+		}
+		return &Number{Value: value, Position: t.pos}, nil
+	}
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if t.text == "+" {
+		return x, nil
+	}
+	return &Neg{X: x, Position: t.pos}, nil
+}
+
+// primary = number | "(" expr ")"
+func (p *parser) primary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &Number{Value: t.text, Position: t.pos}, nil
+	case tokenLParen:
+		if err := p.enter(t); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokenRParen {
+			return nil, &Error{Pos: r.pos, Err: fmt.Errorf("expected ) to match (")}
+		}
+		return x, nil
+	}
+	return nil, unexpected(t)
+}
+
+func (p *parser) enter(t token) error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return &Error{Pos: t.pos, Err: errDepth}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// unexpected return error of token t
+func unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return &Error{Pos: t.pos, Err: fmt.Errorf("unexpected end of expression")}
+	}
+	return &Error{Pos: t.pos, Err: fmt.Errorf("unexpected %q", t.text)}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package expr
+
+import (
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		name, s  string
+		expected []token
+		expPos   int
+	}{
+		{name: "case simple", s: "12+3", expected: []token{
+			{kind: tokenNumber, text: "12", pos: 0}, {kind: tokenOperator, text: "+", pos: 2},
+			{kind: tokenNumber, text: "3", pos: 3}, {kind: tokenEOF, pos: 4},
+		}},
+		{name: "case whitespace", s: " ( 1 ) ", expected: []token{
+			{kind: tokenLParen, text: "(", pos: 1}, {kind: tokenNumber, text: "1", pos: 3},
+			{kind: tokenRParen, text: ")", pos: 5}, {kind: tokenEOF, pos: 7},
+		}},
+		{name: "case invalid character", s: "1+x", expPos: 2},
+		{name: "case float", s: "1.5", expPos: 1},
+	}
+	for _, c := range cases {
+		got, err := tokenize(c.s)
+		if c.expected == nil {
+			e, ok := err.(*Error)
+			if ok == false || e.Pos != c.expPos {
+				t.Errorf("error on: %v\ngot err:\n %v \nexp pos\n %v \n", c.name, err, c.expPos)
+			}
+			continue
+		}
+		if len(got) != len(c.expected) {
+			t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.expected[i] {
+				t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name, s, expected string
+	}{
+		{name: "case precedence", s: "(2+3)*4-10/3", expected: "(((2 + 3) * 4) - (10 / 3))"},
+		{name: "case left associative", s: "10-3-2", expected: "((10 - 3) - 2)"},
+		{name: "case mul before add", s: "1+2*3", expected: "(1 + (2 * 3))"},
+		{name: "case mod", s: "7%3*2", expected: "((7 % 3) * 2)"},
+		{name: "case negative literal", s: "-9223372036854775808", expected: "-9223372036854775808"},
+		{name: "case subtract negative", s: "2--3", expected: "(2 - -3)"},
+		{name: "case unary plus", s: "+2*-3", expected: "(2 * -3)"},
+		{name: "case neg", s: "-(2+3)", expected: "(-(2 + 3))"},
+		{name: "case nested", s: "((1))", expected: "1"},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.s)
+		if err != nil {
+			t.Errorf("error on: %v\ngot err:\n %v \n", c.name, err)
+			continue
+		}
+		if got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		name, s string
+		expPos  int
+	}{
+		{name: "case empty", s: " ", expPos: 0},
+		{name: "case missing operand", s: "1+", expPos: 2},
+		{name: "case missing )", s: "(1+2", expPos: 4},
+		{name: "case extra )", s: "1+2)", expPos: 3},
+		{name: "case two numbers", s: "1 2", expPos: 2},
+		{name: "case leading operator", s: "*2", expPos: 0},
+		{name: "case empty parentheses", s: "1+()", expPos: 3},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.s)
+		e, ok := err.(*Error)
+		if ok == false || e.Pos != c.expPos {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp pos\n %v \n", c.name, err, c.expPos)
+		}
+	}
+}
+
+func TestParseDepth(t *testing.T) {
+	s := ""
+	for i := 0; i <= MaxDepth; i++ {
+		s += "("
+	}
+	_, err := Parse(s + "1")
+	e, ok := err.(*Error)
+	if ok == false || e.Err != errDepth {
+		t.Errorf("error on: depth\ngot err:\n %v \nexp err\n %v \n", err, errDepth)
+	}
+}
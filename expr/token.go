@@ -0,0 +1,70 @@
+package expr
+
+import (
+	"fmt"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+// token is a lexical unit of expression.
+// pos is the byte offset of token in expression, starts from 0
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators are the infix operators accepted by tokenizer
+const operators = "+-*/%"
+
+// tokenize split s into tokens, whitespace is ignored.
+// tokenEOF is always appended so that parser never run out of tokens
+func tokenize(s string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c):
+			start := i
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[start:i], pos: start})
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case isOperator(c):
+			tokens = append(tokens, token{kind: tokenOperator, text: string(c), pos: i})
+			i++
+		default:
+			return nil, &Error{Pos: i, Err: fmt.Errorf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isOperator(c byte) bool {
+	for i := 0; i < len(operators); i++ {
+		if operators[i] == c {
+			return true
+		}
+	}
+	return false
+}
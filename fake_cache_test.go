@@ -1,27 +1,54 @@
 package main
 
+import (
+	"time"
+)
+
 // fakeCacheClient implemented cacheClient interface
-// and used for testing purpose only
+// and used for testing purpose only.
+// ttl is the last TTL a key is set or renewed with
 type fakeCacheClient struct {
-	val map[string]int
+	val map[string]string
+	ttl map[string]time.Duration
+	hit int
 	err error
 }
 
 // NewFakeCache return a new fakeCacheClient
 func NewFakeCache() *fakeCacheClient {
-	v := make(map[string]int)
+	v := make(map[string]string)
 	return &fakeCacheClient{
 		val: v,
 		err: nil,
 	}
 }
-func (f *fakeCacheClient) Get(key string) (int, bool) {
+func (f *fakeCacheClient) Get(key string, ttl time.Duration) (string, bool) {
 	val, ok := f.val[key]
+	if ok {
+		f.setTTL(key, ttl)
+	}
 	return val, ok
 }
 
-func (f *fakeCacheClient) SetWithTTL(key string, value int) {
+func (f *fakeCacheClient) MGet(keys []string, ttls []time.Duration) ([]string, []bool) {
+	values := make([]string, len(keys))
+	oks := make([]bool, len(keys))
+	for i, key := range keys {
+		values[i], oks[i] = f.Get(key, ttls[i])
+	}
+	return values, oks
+}
+
+func (f *fakeCacheClient) SetWithTTL(key string, value string, ttl time.Duration) {
 	f.val[key] = value
+	f.setTTL(key, ttl)
+}
+
+func (f *fakeCacheClient) setTTL(key string, ttl time.Duration) {
+	if f.ttl == nil {
+		f.ttl = make(map[string]time.Duration)
+	}
+	f.ttl[key] = ttl
 }
 
 func (f *fakeCacheClient) Ping() error {
@@ -31,11 +58,11 @@ func (f *fakeCacheClient) Ping() error {
 func (f *fakeCacheClient) Close() {}
 
 func (f *fakeCacheClient) IncrCounter() {
-	f.val["hit"] = f.val["hit"] + 1
+	f.hit++
 }
 
 func (f *fakeCacheClient) GetCounter() int {
-	return f.val["hit"]
+	return f.hit
 }
 
 func (f *fakeCacheClient) GetSize() int {
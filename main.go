@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"github.com/ThisisYang/teltechcc/cacheMe"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -14,29 +15,129 @@ import (
 
 var cache cacheClient
 
+var errCacheLimit = fmt.Errorf("Invalid cache limit. Integer no less than 0 only, 0 is no limit")
+var errCacheShards = fmt.Errorf("Invalid cache shards. Integer no less than 1 only")
+var errSweepInterval = fmt.Errorf("Invalid cache sweep interval. Duration greater than 0 only")
+
+// cacheFlags are flags of cache, shared by the server and `teltechcc repl`
+type cacheFlags struct {
+	redisURL string
+	ttl      time.Duration
+	opTTL    string
+	expire   string
+	lifetime time.Duration
+	entries  int
+	maxBytes int64
+	shards   int
+	sweep    time.Duration
+}
+
+// register define cache flags on fs, usage of redis differs between the server and repl
+func (f *cacheFlags) register(fs *flag.FlagSet, redisUsage string) {
+	fs.StringVar(&f.redisURL, "redis", "", redisUsage)
+	fs.DurationVar(&f.ttl, "ttl", defaultTTL, "how long result is cached, renewed on every hit. 0 is no expiry")
+	fs.StringVar(&f.opTTL, "op-ttl", "", "TTL of operations overriding --ttl, like `add=10m,div=0`")
+	fs.StringVar(&f.expire, "expiration", cacheMe.Sliding, "expiration policy of cache. sliding (TTL is renewed on every hit) or absolute")
+	fs.DurationVar(&f.lifetime, "max-lifetime", 0, "max lifetime of a cached result since it was calculated, no matter how often it is hit. 0 is no limit")
+	fs.IntVar(&f.entries, "cache-max-entries", 0, "max number of results in local memory, least recently used one is evicted. 0 is no limit")
+	fs.Int64Var(&f.maxBytes, "cache-max-bytes", 0, "approximate max bytes of results in local memory, least recently used one is evicted. 0 is no limit")
+	fs.IntVar(&f.shards, "cache-shards", cacheMe.DefaultShards, "number of shards of local memory, each shard has its own lock. limits of entries and bytes are of all shards")
+	fs.DurationVar(&f.sweep, "cache-sweep-interval", cacheMe.DefaultSweepInterval, "how often expired results are removed from local memory in background")
+}
+
+// newCache validate flags and return cache configured by them, defaultTTL and opTTLs are set as well
+func (f *cacheFlags) newCache() (cacheClient, error) {
+	if f.ttl < 0 {
+		return nil, errTTL
+	}
+	ttls, err := parseOpTTLs(f.opTTL)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := cacheMe.NewPolicy(f.expire, f.lifetime)
+	if err != nil {
+		return nil, err
+	}
+	if f.entries < 0 || f.maxBytes < 0 {
+		return nil, errCacheLimit
+	}
+	if f.shards < 1 {
+		return nil, errCacheShards
+	}
+	if f.sweep <= 0 {
+		return nil, errSweepInterval
+	}
+	defaultTTL, opTTLs = f.ttl, ttls
+
+	if f.redisURL != "" {
+		return cacheMe.NewRedisClient(f.redisURL, policy), nil
+	}
+	return cacheMe.NewDefaultClient(policy, cacheMe.MaxEntries(f.entries), cacheMe.MaxBytes(f.maxBytes),
+		cacheMe.Shards(f.shards), cacheMe.SweepInterval(f.sweep)), nil
+}
+
 func main() {
+	// client subcommands, like `teltechcc calc add 2 5`, talk to a running server
+	if len(os.Args) > 1 && clientCommands[os.Args[1]] != nil {
+		setUpLogger(false)
+		os.Exit(runClient(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	// repl calculates locally, see runREPL
+	if len(os.Args) > 1 && os.Args[1] == "repl" {
+		setUpLogger(false)
+		os.Exit(runREPL(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	var (
-		ip       = flag.String("ip", "0.0.0.0", "IP server bind to")
-		port     = flag.Int("port", 8000, "port server listen on")
-		redisURL = flag.String("redis", "", "redis url. for example: `redis://localhost:6379`. If not set, will use local memory instead of redis as cache")
-		debug    = flag.Bool("debug", false, "boolean field, set to enable debug mode for both gin server and app")
-		flush    = flag.Bool("flush", false, "boolean, set true if to flush db on boot")
+		ip        = flag.String("ip", "0.0.0.0", "IP server bind to")
+		port      = flag.Int("port", 8000, "port server listen on")
+		debug     = flag.Bool("debug", false, "boolean field, set to enable debug mode for both gin server and app")
+		flush     = flag.Bool("flush", false, "boolean, set true if to flush db on boot")
+		precision = flag.String("precision", precisionInt, "default precision if not set in query string. int (64 bit) or big (arbitrary precision)")
+		scale     = flag.Int("scale", defaultScale, "default number of digits after decimal point of decimal result if not set in query string")
+		rounding  = flag.String("rounding", defaultRounding, "default rounding mode of decimal result if not set in query string. one of up, down, ceiling, floor, half-up, half-down, half-even")
+		operands  = flag.Int("max-operands", maxOperands, "max number of values accepted by add, subtract, multiply and divide in a single request. at least 2")
+		withDocs  = flag.Bool("docs", serveDocs, "boolean, set to serve docs page of /openapi.json at /docs")
+		tcpPort   = flag.Int("tcp-port", 0, "port TCP line protocol server listen on, like `ADD 2 5`. If not set, TCP server is disabled")
 	)
+	var cf cacheFlags
+	cf.register(flag.CommandLine, "redis url. for example: `redis://localhost:6379`. If not set, will use local memory instead of redis as cache")
 	flag.Parse()
 
 	setUpLogger(*debug)
 
+	if validPrecision(*precision) == false {
+		Error.Fatalln(errPrecision)
+	}
+	defaultPrecision = *precision
+
+	if validScale(*scale) == false {
+		Error.Fatalln(errScale)
+	}
+	defaultScale = *scale
+
+	if validRounding(*rounding) == false {
+		Error.Fatalln(errRounding)
+	}
+	defaultRounding = *rounding
+
+	if *operands < 2 {
+		Error.Fatalln(errMaxOperands)
+	}
+	maxOperands = *operands
+	serveDocs = *withDocs
+
+	var err error
+	cache, err = cf.newCache()
+	if err != nil {
+		Error.Fatalln(err)
+	}
+
 	// if debug is false, set gin server to release mode as well
 	if *debug == false {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	if *redisURL != "" {
-		cache = cacheMe.NewRedisClient(*redisURL)
-	} else {
-		cache = cacheMe.NewDefaultClient()
-	}
-
 	if *flush {
 		cache.Flush()
 	}
@@ -51,12 +152,27 @@ func main() {
 		}
 	}()
 
+	var t *tcpServer
+	if *tcpPort != 0 {
+		t = newTCPServer(*ip, *tcpPort)
+		go func() {
+			if err := t.ListenAndServe(); err != errTCPServerClosed {
+				Warning.Println("tcp server closed with err: ", err)
+			}
+		}()
+	}
+
 	waitSingal()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := s.Shutdown(ctx); err != nil {
 		Warning.Println("Server shutdown failure: ", err)
 	}
+	if t != nil {
+		if err := t.Shutdown(ctx); err != nil {
+			Warning.Println("TCP server shutdown failure: ", err)
+		}
+	}
 
 }
 
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimeJSON     = binding.MIMEJSON
+	mimeXML      = binding.MIMEXML
+	mimeYAML     = "application/x-yaml"
+	mimeMsgPack  = binding.MIMEMSGPACK
+	mimeProtoBuf = binding.MIMEPROTOBUF
+)
+
+// offered are the response formats, the first one is the default
+var offered = []string{mimeJSON, mimeXML, mimeYAML, mimeMsgPack, mimeProtoBuf}
+
+// mimeAliases are accepted as well as the offered ones
+var mimeAliases = map[string]string{
+	binding.MIMEXML2:       mimeXML,
+	"application/yaml":     mimeYAML,
+	"text/yaml":            mimeYAML,
+	"text/x-yaml":          mimeYAML,
+	binding.MIMEMSGPACK2:   mimeMsgPack,
+	"application/protobuf": mimeProtoBuf,
+	// media types of problem details, see respondProblem
+	mimeProblemJSON: mimeJSON,
+	mimeProblemXML:  mimeXML,
+}
+
+var errNotAcceptable = fmt.Errorf("Not acceptable. One of %v only", strings.Join(offered, ", "))
+
+// formatKey is the key of negotiated format in gin.Context
+const formatKey = "format"
+
+// negotiate is the middleware choosing response format by Accept header.
+// 406 is returned in JSON if none of the accepted formats is offered
+func negotiate(ctx *gin.Context) {
+	accept := ctx.GetHeader("Accept")
+	format := negotiateFormat(accept)
+	if format == "" {
+		respondError(ctx, &fieldError{field: "Accept", value: accept, err: errNotAcceptable})
+		ctx.Abort()
+		return
+	}
+	ctx.Set(formatKey, format)
+	ctx.Next()
+}
+
+// acceptFormat set format of ctx by Accept header like negotiate, but never respond 406.
+// problem is responded as application/problem+json if none of the offered format is accepted
+func acceptFormat(ctx *gin.Context) {
+	if format := negotiateFormat(ctx.GetHeader("Accept")); format != "" {
+		ctx.Set(formatKey, format)
+	}
+}
+
+// negotiateFormat return the offered format of the highest quality value in accept.
+// JSON is returned if accept is empty or */*.
+// quality value of a format is the one of the most specific range matching it,
+// so application/xml;q=0 refuses XML even with */*, and application/xml overrides application/*;q=0.
+// order of accept only breaks ties of the same quality value
+func negotiateFormat(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+	ranges := parseAccept(accept)
+	format, quality, index := "", 0.0, 0
+	for _, o := range offered {
+		q, i, ok := matchRange(ranges, o)
+		// range earlier in accept wins on the same quality value, then the format offered first
+		if ok == false || q == 0 || q < quality || (q == quality && i >= index) {
+			continue
+		}
+		format, quality, index = o, q, i
+	}
+	return format
+}
+
+// matchRange return quality value and index of the most specific range of ranges matching offered format o,
+// false if none of them matches. lower quality value wins among ranges of the same specificity,
+// so that text/xml;q=0 refuses XML even if application/xml is accepted
+func matchRange(ranges []acceptRange, o string) (float64, int, bool) {
+	best, specificity := -1, -1
+	for i, r := range ranges {
+		s := mimeSpecificity(r.mime, o)
+		if s < 0 || s < specificity || (s == specificity && r.q >= ranges[best].q) {
+			continue
+		}
+		best, specificity = i, s
+	}
+	if best < 0 {
+		return 0, 0, false
+	}
+	return ranges[best].q, best, true
+}
+
+// acceptRange is a media range of Accept and its quality value
+type acceptRange struct {
+	mime string
+	q    float64
+}
+
+// parseAccept return media ranges of accept in order, aliases are replaced by offered ones.
+// quality value is 1 if not set, range of invalid quality value is skipped
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := acceptRange{mime: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if alias, ok := mimeAliases[r.mime]; ok {
+			r.mime = alias
+		}
+		valid := true
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || strings.ToLower(strings.TrimSpace(kv[0])) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			r.q = q
+		}
+		if valid && r.mime != "" {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// mimeSpecificity return how specific media range mime is for offered format o:
+// 2 if it is o, 1 if it is type of o like application/*, 0 if it is */*, -1 if o is not in it
+func mimeSpecificity(mime, o string) int {
+	switch {
+	case mime == o:
+		return 2
+	case mime == "*/*":
+		return 0
+	case strings.HasSuffix(mime, "/*") && strings.HasPrefix(o, mime[:len(mime)-1]):
+		return 1
+	}
+	return -1
+}
+
+// respond render data, gin.H or []gin.H, in negotiated format and API version of the route
+func respond(ctx *gin.Context, code int, data interface{}) {
+	data = versioned(ctx, data)
+	switch ctx.GetString(formatKey) {
+	case mimeXML:
+		ctx.XML(code, xmlData(data))
+	case mimeYAML:
+		ctx.YAML(code, data)
+	case mimeMsgPack:
+		ctx.Render(code, render.MsgPack{Data: data})
+	case mimeProtoBuf:
+		ctx.Render(code, protoBuf{Data: protoData(data)})
+	default:
+		ctx.JSON(code, data)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang/protobuf/proto"
+	"github.com/ugorji/go/codec"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func performRequestWithAccept(r http.Handler, method, path, accept, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Content-Type", mimeJSON)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		name, accept, expected string
+	}{
+		{name: "case empty", accept: "", expected: mimeJSON},
+		{name: "case any", accept: "*/*", expected: mimeJSON},
+		{name: "case xml", accept: "application/xml", expected: mimeXML},
+		{name: "case xml alias", accept: "text/xml", expected: mimeXML},
+		{name: "case yaml", accept: "application/x-yaml", expected: mimeYAML},
+		{name: "case yaml alias", accept: "text/yaml", expected: mimeYAML},
+		{name: "case msgpack", accept: "application/msgpack", expected: mimeMsgPack},
+		{name: "case protobuf", accept: "application/x-protobuf", expected: mimeProtoBuf},
+		{name: "case order", accept: "text/html, application/x-msgpack, application/json", expected: mimeMsgPack},
+		{name: "case quality", accept: "text/html, application/x-msgpack;q=0.9, application/json", expected: mimeJSON},
+		{name: "case higher quality later", accept: "application/xml;q=0.1, application/json;q=1", expected: mimeJSON},
+		{name: "case refused", accept: "application/json;q=0, application/xml", expected: mimeXML},
+		{name: "case refused by wildcard", accept: "application/json;q=0, */*", expected: mimeXML},
+		{name: "case refused alias", accept: "text/xml;q=0, application/xml", expected: ""},
+		{name: "case refused by type wildcard", accept: "application/*;q=0, */*", expected: ""},
+		{name: "case specific overrides refused wildcard", accept: "application/*;q=0, application/xml", expected: mimeXML},
+		{name: "case specific quality", accept: "application/json;q=0.2, application/*;q=0.9", expected: mimeXML},
+		{name: "case specific over any", accept: "*/*;q=0.1, application/x-yaml;q=0.5", expected: mimeYAML},
+		{name: "case all refused", accept: "application/json;q=0", expected: ""},
+		{name: "case tie", accept: "application/x-yaml;q=0.5, application/xml;q=0.5", expected: mimeYAML},
+		{name: "case invalid quality", accept: "application/xml;q=2, application/x-yaml", expected: mimeYAML},
+		{name: "case wildcard subtype", accept: "text/*", expected: ""},
+		{name: "case wildcard subtype json", accept: "application/*", expected: mimeJSON},
+		{name: "case case insensitive", accept: "Application/XML", expected: mimeXML},
+		{name: "case unsupported", accept: "text/html", expected: ""},
+	}
+	for _, c := range cases {
+		got := negotiateFormat(c.accept)
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url, accept, body string
+		method                  string
+		expStatusCode           int
+		expContentType          string
+		expBody                 string
+	}{
+		{
+			name: "case xml", method: "GET", url: "/add?x=1&y=3", accept: "application/xml", expStatusCode: 200,
+			expContentType: "application/xml; charset=utf-8",
+			expBody:        "<response><action>add</action><answer>4</answer><cached>false</cached><x>1</x><y>3</y></response>",
+		},
+		{
+			name: "case xml values", method: "GET", url: "/add?values=1,2", accept: "text/xml", expStatusCode: 200,
+			expContentType: "application/xml; charset=utf-8",
+			expBody:        "<response><action>add</action><answer>3</answer><cached>false</cached><values>1</values><values>2</values></response>",
+		},
+		{
+			name: "case xml error", method: "GET", url: "/add?x=1", accept: "application/xml", expStatusCode: 400,
+			expContentType: "application/problem+xml; charset=utf-8",
+			expBody: `<problem xmlns="urn:ietf:rfc:7807"><code>missing_operand</code><detail>y is not provided</detail>` +
+				`<field>y</field><instance>/add?x=1</instance><request_id>test-request</request_id><status>400</status>` +
+				`<title>Missing operand</title><type>urn:teltechcc:problem:missing_operand</type></problem>`,
+		},
+		{
+			name: "case problem json", method: "GET", url: "/add?x=1", accept: "application/problem+json", expStatusCode: 400,
+			expContentType: "application/problem+json",
+			expBody: `{"code":"missing_operand","detail":"y is not provided","field":"y","instance":"/add?x=1",` +
+				`"request_id":"test-request","status":400,"title":"Missing operand","type":"urn:teltechcc:problem:missing_operand"}`,
+		},
+		{
+			name: "case yaml error", method: "GET", url: "/add?x=1", accept: "application/x-yaml", expStatusCode: 400,
+			expContentType: "application/x-yaml; charset=utf-8",
+			expBody: "code: missing_operand\ndetail: y is not provided\nfield: \"y\"\ninstance: /add?x=1\n" +
+				"request_id: test-request\nstatus: 400\ntitle: Missing operand\ntype: urn:teltechcc:problem:missing_operand\n",
+		},
+		{
+			name: "case xml batch", method: "POST", url: "/batch", accept: "application/xml", expStatusCode: 200,
+			body:           `[{"op": "add", "x": 1, "y": 3}, {"op": "div", "x": 1, "y": 0}]`,
+			expContentType: "application/xml; charset=utf-8",
+			expBody: "<responses><response><action>add</action><answer>4</answer><cached>false</cached><x>1</x><y>3</y></response>" +
+				"<response><code>divide_by_zero</code><detail>Divide by zero</detail><field>y</field><request_id>test-request</request_id>" +
+				"<status>400</status><title>Divide by zero</title><type>urn:teltechcc:problem:divide_by_zero</type><value>0</value></response></responses>",
+		},
+		{
+			name: "case yaml", method: "GET", url: "/divide?x=-7&y=2", accept: "application/x-yaml", expStatusCode: 200,
+			expContentType: "application/x-yaml; charset=utf-8",
+			expBody:        "action: divide\nanswer: -4\ncached: false\nmode: floor\nremainder: 1\nx: -7\n\"y\": 2\n",
+		},
+		{
+			name: "case not acceptable", method: "GET", url: "/add?x=1&y=3", accept: "text/html", expStatusCode: 406,
+			expContentType: "application/problem+json",
+			expBody: `{"code":"not_acceptable","detail":"` + errNotAcceptable.Error() + `","field":"Accept","instance":"/add?x=1\u0026y=3",` +
+				`"request_id":"test-request","status":406,"title":"Not acceptable","type":"urn:teltechcc:problem:not_acceptable","value":"text/html"}`,
+		},
+		{
+			name: "case not found not acceptable", method: "GET", url: "/nope", accept: "text/html", expStatusCode: 404,
+			expContentType: "application/problem+json",
+			expBody: `{"code":"not_found","detail":"` + errNotFound.Error() + `","field":"path","instance":"/nope",` +
+				`"request_id":"test-request","status":404,"title":"Not found","type":"urn:teltechcc:problem:not_found","value":"/nope"}`,
+		},
+		{
+			name: "case not found xml", method: "GET", url: "/nope", accept: "application/xml", expStatusCode: 404,
+			expContentType: "application/problem+xml; charset=utf-8",
+			expBody: `<problem xmlns="urn:ietf:rfc:7807"><code>not_found</code><detail>` + errNotFound.Error() + `</detail>` +
+				`<field>path</field><instance>/nope</instance><request_id>test-request</request_id><status>404</status>` +
+				`<title>Not found</title><type>urn:teltechcc:problem:not_found</type><value>/nope</value></problem>`,
+		},
+		{
+			name: "case method not allowed not acceptable", method: "DELETE", url: "/add", accept: "text/html", expStatusCode: 405,
+			expContentType: "application/problem+json",
+			expBody: `{"code":"method_not_allowed","detail":"` + errMethodNotAllowed.Error() + `","field":"method","instance":"/add",` +
+				`"request_id":"test-request","status":405,"title":"Method not allowed","type":"urn:teltechcc:problem:method_not_allowed","value":"DELETE"}`,
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = NewFakeCache()
+		w := performRequestWithAccept(router, c.method, c.url, c.accept, c.body)
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if got := w.Header().Get("Content-Type"); got != c.expContentType {
+			t.Errorf("error on: %v\ngot content type:\n %v \nexp content type\n %v \n", c.name, got, c.expContentType)
+		}
+		if w.Body.String() != c.expBody {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), c.expBody)
+		}
+	}
+}
+
+func TestNegotiateMsgPack(t *testing.T) {
+	setUpLogger(false)
+	cache = NewFakeCache()
+	router := newRouter()
+	w := performRequestWithAccept(router, "GET", "/add?x=9223372036854775807&y=1&precision=big", mimeMsgPack, "")
+
+	var got map[string]interface{}
+	h := new(codec.MsgpackHandle)
+	h.RawToString = true
+	err := codec.NewDecoderBytes(w.Body.Bytes(), h).Decode(&got)
+	if err != nil {
+		t.Fatalf("error on: decode msgpack\n%v\n", err)
+	}
+	exp := map[string]interface{}{
+		"action": "add", "x": "9223372036854775807", "y": "1", "answer": "9223372036854775808", "cached": false, "precision": "big",
+	}
+	if reflect.DeepEqual(got, exp) == false {
+		t.Errorf("error on: msgpack\ngot:\n %v \nexp\n %v \n", got, exp)
+	}
+}
+
+func TestNegotiateProtoBuf(t *testing.T) {
+	setUpLogger(false)
+	cache = &fakeCacheClient{val: map[string]string{"add:1:3": "4"}}
+	router := newRouter()
+
+	w := performRequestWithAccept(router, "GET", "/rational/add?x=1/2&y=1/3&scale=2", mimeProtoBuf, "")
+	got := &Response{}
+	err := proto.Unmarshal(w.Body.Bytes(), got)
+	if err != nil {
+		t.Fatalf("error on: decode protobuf\n%v\n", err)
+	}
+	exp := &Response{
+		Action: "add", X: "1/2", Y: "1/3", Answer: "5/6", Type: "rational",
+		Numerator: "5", Denominator: "6", Decimal: "0.83",
+	}
+	if proto.Equal(got, exp) == false {
+		t.Errorf("error on: protobuf\ngot:\n %v \nexp\n %v \n", got, exp)
+	}
+
+	w = performRequestWithAccept(router, "POST", "/batch", mimeProtoBuf, `[{"op": "add", "x": 1, "y": 3}, {"op": "add", "x": 1}]`)
+	gotBatch := &BatchResponse{}
+	err = proto.Unmarshal(w.Body.Bytes(), gotBatch)
+	if err != nil {
+		t.Fatalf("error on: decode protobuf batch\n%v\n", err)
+	}
+	expBatch := &BatchResponse{Results: []*Response{
+		{Action: "add", X: "1", Y: "3", Answer: "4", Cached: true},
+		{
+			Type: "urn:teltechcc:problem:missing_operand", Title: "Missing operand", Status: 400, Detail: errMissY.Error(),
+			Code: codeMissingOperand, Field: "y", RequestID: testRequestID,
+		},
+	}}
+	if proto.Equal(gotBatch, expBatch) == false {
+		t.Errorf("error on: protobuf batch\ngot:\n %v \nexp\n %v \n", gotBatch, expBatch)
+	}
+}
+
+func TestNewResponse(t *testing.T) {
+	// every key used by handlers has to be in schema
+	h := gin.H{
+		"action": "eval", "x": int64(1), "y": "2", "values": []interface{}{int64(1), "2"}, "answer": int64(3), "cached": true,
+		"remainder": int64(0), "mode": "floor", "type": "decimal", "scale": 2, "rounding": "half-even", "precision": "big",
+		"numerator": "1", "denominator": "2", "decimal": "0.5", "code": "overflow", "pos": 3, "expr": "1+2",
+		"nodes": 1, "hit": 1, "cache": "OK", "size": 2, "title": "Integer overflow", "status": 422, "detail": "overflow",
+		"instance": "/eval?expr=1%2B2", "field": "expr", "value": "1+2", "request_id": "id",
+	}
+	got := newResponse(h)
+	exp := &Response{
+		Action: "eval", X: "1", Y: "2", Values: []string{"1", "2"}, Answer: "3", Cached: true,
+		Remainder: "0", Mode: "floor", Type: "decimal", Scale: 2, Rounding: "half-even", Precision: "big",
+		Numerator: "1", Denominator: "2", Decimal: "0.5", Code: "overflow", Pos: 3, Expr: "1+2",
+		Nodes: 1, Hit: 1, Cache: "OK", Size: 2, Title: "Integer overflow", Status: 422, Detail: "overflow",
+		Instance: "/eval?expr=1%2B2", Field: "expr", Value: "1+2", RequestID: "id",
+	}
+	if reflect.DeepEqual(got, exp) == false {
+		t.Errorf("error on: new response\ngot:\n %v \nexp\n %v \n", got, exp)
+	}
+}
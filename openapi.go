@@ -0,0 +1,392 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// serveDocs enable the docs page at /docs, can be changed via --docs flag
+var serveDocs = false
+
+// openAPIHandler serve OpenAPI document of routes registered in r.
+// document is generated from r.Routes() on request, so that it is always in sync with router
+func openAPIHandler(r *gin.Engine) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(200, openAPI(r.Routes()))
+	}
+}
+
+// openAPI return OpenAPI 3 document of routes
+func openAPI(routes gin.RoutesInfo) gin.H {
+	paths := gin.H{}
+	for _, route := range routes {
+		item, ok := paths[route.Path].(gin.H)
+		if ok == false {
+			item = gin.H{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = describeRoute(route.Method, route.Path)
+	}
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "teltechcc",
+			"description": "Calculator with cached results. Unversioned routes are aliases of /v1, which is deprecated.",
+			"version":     v2,
+		},
+		"paths":      paths,
+		"components": openAPIComponents(),
+	}
+}
+
+// describeRoute return OpenAPI operation of route.
+// route is matched by path without version prefix, the same way as addRoutes registers it.
+// operation without description is returned for unknown route
+func describeRoute(method, path string) gin.H {
+	version, route := v1, path
+	for _, v := range []string{v1, v2} {
+		if strings.HasPrefix(path, "/"+v+"/") {
+			version, route = v, strings.TrimPrefix(path, "/"+v)
+		}
+	}
+	var op gin.H
+	switch {
+	case route == "/openapi.json":
+		return gin.H{
+			"summary":   "OpenAPI document of this server",
+			"responses": gin.H{"200": gin.H{"description": "OpenAPI 3 document", "content": gin.H{mimeJSON: gin.H{"schema": gin.H{"type": "object"}}}}},
+		}
+	case route == "/rpc":
+		return gin.H{
+			"summary":    "JSON-RPC 2.0 of calculation methods, like add and subtract, and health",
+			"parameters": []gin.H{cacheControlParam()},
+			"requestBody": gin.H{"required": true, "content": gin.H{mimeJSON: gin.H{"schema": gin.H{
+				"oneOf": []gin.H{schemaRef("RPCRequest"), {"type": "array", "items": schemaRef("RPCRequest")}},
+			}}}},
+			"responses": gin.H{
+				"200": gin.H{"description": "JSON-RPC response, or array of them for batch", "content": gin.H{mimeJSON: gin.H{"schema": gin.H{
+					"oneOf": []gin.H{schemaRef("RPCResponse"), {"type": "array", "items": schemaRef("RPCResponse")}},
+				}}}},
+				"204": gin.H{"description": "All requests are notifications"},
+				"400": gin.H{"$ref": "#/components/responses/Problem"},
+			},
+		}
+	case route == "/ws":
+		return gin.H{
+			"summary":     "WebSocket session of calculation messages, like {\"id\": 1, \"op\": \"add\", \"params\": {\"x\": 2, \"y\": 5}}",
+			"description": "op and params are the same as method and params of /rpc. Reply is {id, type: result, result} or {id, type: error, error}, error is problem details.",
+			"parameters": []gin.H{
+				{"name": "Upgrade", "in": "header", "required": true, "schema": gin.H{"type": "string", "enum": []string{"websocket"}}},
+				{"name": "Sec-WebSocket-Key", "in": "header", "required": true, "schema": gin.H{"type": "string"}},
+				{"name": "Sec-WebSocket-Version", "in": "header", "required": true, "schema": gin.H{"type": "string", "enum": []string{"13"}}},
+			},
+			"responses": gin.H{
+				"101": gin.H{"description": "Switching Protocols"},
+				"400": gin.H{"$ref": "#/components/responses/Problem"},
+				"405": gin.H{"$ref": "#/components/responses/Problem"},
+			},
+		}
+	case route == "/events":
+		return gin.H{
+			"summary": "Server-Sent Events of every calculation and periodic cache stats",
+			"description": "Events are calc (op, type, operands, result, cached, error and latency_us), stats (same as /health) " +
+				"and dropped (number of events dropped since last report, if the subscriber is too slow).",
+			"responses": gin.H{"200": gin.H{"description": "Event stream", "content": gin.H{sse.ContentType: gin.H{"schema": gin.H{"type": "string"}}}}},
+		}
+	case route == "/docs":
+		return gin.H{
+			"summary":   "Docs page of this server",
+			"responses": gin.H{"200": gin.H{"description": "HTML page", "content": gin.H{"text/html": gin.H{}}}},
+		}
+	case route == "/health":
+		op = gin.H{
+			"summary":   "Cache status",
+			"responses": gin.H{"200": jsonResponse("Cache status, hit and size are omitted if cache is down. evicted and expired are of local memory only", schemaRef("Health"))},
+		}
+	case route == "/eval":
+		op = gin.H{
+			"summary":    "Evaluate arithmetic expression of + - * / % and parentheses",
+			"parameters": []gin.H{queryParam("expr", "URL encoded expression, like (2%2B3)*4", true, gin.H{"type": "string", "maxLength": maxExprLength}), cacheControlParam()},
+			"responses":  errorResponses(gin.H{"200": jsonResponse("Result", resultRef(version))}, "400", "422"),
+		}
+	case route == "/batch" && method == "POST":
+		op = gin.H{
+			"summary":    "Calculate a batch of binary operations with a single cache round trip",
+			"parameters": []gin.H{cacheControlParam()},
+			"requestBody": gin.H{"required": true, "content": gin.H{mimeJSON: gin.H{"schema": gin.H{
+				"type": "array", "maxItems": maxBatchSize, "items": schemaRef("BatchItem"),
+			}}}},
+			"responses": errorResponses(gin.H{"200": jsonResponse("Result or problem details of each item, in order of request", gin.H{
+				"type": "array", "items": gin.H{"oneOf": []gin.H{resultRef(version), schemaRef("Problem")}},
+			})}, "400"),
+		}
+	case strings.HasPrefix(route, "/"+typeRational+"/"):
+		calc, ok := routeOp(strings.TrimPrefix(route, "/"+typeRational))
+		if ok == false {
+			return gin.H{"responses": gin.H{"default": gin.H{"description": ""}}}
+		}
+		params := append(operandParams(), queryParam("scale", "number of digits of decimal rendering, decimal is omitted if not set", false,
+			gin.H{"type": "integer", "minimum": 0, "maximum": maxDecimalScale}), cacheControlParam())
+		op = gin.H{
+			"summary":    fmt.Sprintf("Exact %v of fractions or decimal numbers", calc.Action),
+			"parameters": params,
+			"responses":  errorResponses(gin.H{"200": jsonResponse("Result as reduced fraction", resultRef(version))}, "400"),
+		}
+	default:
+		calc, ok := routeOp(route)
+		if ok == false {
+			return gin.H{"responses": gin.H{"default": gin.H{"description": ""}}}
+		}
+		op = describeOp(calc, version, method)
+	}
+	op["tags"] = []string{version}
+	if version == v1 {
+		op["deprecated"] = true
+	}
+	return op
+}
+
+// routeOp return registered operation of route
+func routeOp(route string) (ops.Operation, bool) {
+	for _, op := range ops.All() {
+		if op.Route == route {
+			return op, true
+		}
+	}
+	return ops.Operation{}, false
+}
+
+// describeOp return OpenAPI operation of calculation endpoint of op.
+// parameters are derived from op, like precision is only available if op has Big
+func describeOp(op ops.Operation, version, method string) gin.H {
+	summary := fmt.Sprintf("%v of %d", op.Action, op.Arity)
+	if op.Variadic {
+		summary = fmt.Sprintf("%v of %d to %d", op.Action, op.Arity, maxOperands)
+	}
+	summary += " operands, 64 bit integer by default"
+	var params []gin.H
+	if method == "GET" {
+		params = operandParams()
+	}
+	if op.Name == "div" {
+		params = append(params, queryParam("mode", "rounding mode of integer division", false, enumSchema(ops.DivModes, defaultDivMode)))
+	}
+	if op.Big != nil {
+		params = append(params, queryParam("precision", "int (64 bit) or big (arbitrary precision)", false,
+			enumSchema([]string{precisionInt, precisionBig}, defaultPrecision)))
+	}
+	if op.Rat != nil {
+		params = append(params,
+			queryParam("type", "integer or decimal", false, enumSchema([]string{typeInteger, typeDecimal}, typeInteger)),
+			queryParam("scale", "number of digits after decimal point of decimal result", false,
+				gin.H{"type": "integer", "minimum": 0, "maximum": maxDecimalScale, "default": defaultScale}),
+			queryParam("rounding", "rounding mode of decimal result", false, enumSchema(roundingModes, defaultRounding)),
+		)
+	}
+	params = append(params, cacheControlParam())
+	doc := gin.H{
+		"summary":    summary,
+		"parameters": params,
+		"responses":  errorResponses(gin.H{"200": jsonResponse("Result", resultRef(version))}, "400", "422"),
+	}
+	if method == "POST" {
+		content := gin.H{}
+		for _, mime := range []string{mimeJSON, mimeXML, mimeMsgPack, "application/x-www-form-urlencoded"} {
+			content[mime] = gin.H{"schema": schemaRef("Operands")}
+		}
+		doc["requestBody"] = gin.H{"required": true, "content": content}
+	}
+	return doc
+}
+
+// operandParams are x, y, v and values in query string, see getOperands
+func operandParams() []gin.H {
+	return []gin.H{
+		queryParam("x", "first operand", false, gin.H{"type": "string"}),
+		queryParam("y", "second operand", false, gin.H{"type": "string"}),
+		queryParam("v", "repeated operands, x and y are ignored if set", false, gin.H{"type": "array", "items": gin.H{"type": "string"}}),
+		queryParam("values", "comma separated operands, x and y are ignored if set", false, gin.H{"type": "string"}),
+	}
+}
+
+// cacheControlParam is Cache-Control of calculation endpoints, see requestTTL
+func cacheControlParam() gin.H {
+	return gin.H{
+		"name": "Cache-Control", "in": "header", "required": false, "schema": gin.H{"type": "string"},
+		"description": fmt.Sprintf("max-age=N caches result for N seconds (up to %d) instead of the TTL of the server. max-age=0, no-cache or no-store bypass cache", maxMaxAge),
+	}
+}
+
+func queryParam(name, description string, required bool, schema gin.H) gin.H {
+	return gin.H{"name": name, "in": "query", "description": description, "required": required, "schema": schema}
+}
+
+func enumSchema(values []string, defaultValue string) gin.H {
+	return gin.H{"type": "string", "enum": values, "default": defaultValue}
+}
+
+func schemaRef(name string) gin.H {
+	return gin.H{"$ref": "#/components/schemas/" + name}
+}
+
+// resultRef return schema of calculation result of version
+func resultRef(version string) gin.H {
+	if version == v2 {
+		return schemaRef("ResultV2")
+	}
+	return schemaRef("Result")
+}
+
+func jsonResponse(description string, schema gin.H) gin.H {
+	return gin.H{
+		"description": description,
+		"headers":     gin.H{requestIDHeader: gin.H{"$ref": "#/components/headers/RequestID"}},
+		"content":     gin.H{mimeJSON: gin.H{"schema": schema}},
+	}
+}
+
+// errorResponses add problem responses of status to responses.
+// 405 and 406 are returned by every route
+func errorResponses(responses gin.H, status ...string) gin.H {
+	for _, s := range append(status, "405", "406") {
+		responses[s] = gin.H{"$ref": "#/components/responses/Problem"}
+	}
+	return responses
+}
+
+// openAPIComponents are schemas shared by routes.
+// field names are the keys of gin.H built by handlers, see response.proto
+func openAPIComponents() gin.H {
+	number := gin.H{"oneOf": []gin.H{{"type": "integer", "format": "int64"}, {"type": "string"}},
+		"description": "integer in 64 bit, string otherwise"}
+	str := gin.H{"type": "string"}
+	integer := gin.H{"type": "integer"}
+	result := gin.H{
+		"action": str, "x": number, "y": number, "values": gin.H{"type": "array", "items": number}, "answer": number,
+		"cached": gin.H{"type": "boolean"}, "remainder": number, "mode": str, "type": str, "scale": integer, "rounding": str,
+		"precision": str, "numerator": str, "denominator": str, "decimal": str, "expr": str, "nodes": integer, "hit": integer,
+	}
+	resultV2 := gin.H{"op": str}
+	for k, v := range result {
+		resultV2[k] = v
+		if k == "x" || k == "y" || k == "answer" || k == "remainder" {
+			resultV2[k] = str
+		}
+	}
+	resultV2["values"] = gin.H{"type": "array", "items": str}
+
+	codes := make([]string, 0, len(problemTypes))
+	for code := range problemTypes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return gin.H{
+		"schemas": gin.H{
+			"Result":   gin.H{"type": "object", "properties": result},
+			"ResultV2": gin.H{"type": "object", "properties": resultV2},
+			"Problem": gin.H{"type": "object", "description": "RFC 7807 problem details", "properties": gin.H{
+				"type": str, "title": str, "status": integer, "detail": str, "instance": str,
+				"code": gin.H{"type": "string", "enum": codes}, "field": str, "value": str, "request_id": str,
+				"pos": gin.H{"type": "integer", "description": "position of the offending token of /eval"},
+			}},
+			"Health": gin.H{"type": "object", "properties": gin.H{
+				"cache": gin.H{"type": "string", "description": "OK or error of cache"}, "hit": integer, "size": integer,
+				"evicted": integer, "expired": integer,
+			}},
+			"Operands": gin.H{"type": "object", "properties": gin.H{
+				"x": number, "y": number, "values": gin.H{"type": "array", "items": number},
+			}},
+			"BatchItem": gin.H{"type": "object", "required": []string{"op", "x", "y"}, "properties": gin.H{
+				"op": gin.H{"type": "string", "description": "name or action of operation, like sub or subtract"}, "x": number, "y": number,
+			}},
+			"RPCRequest": gin.H{"type": "object", "required": []string{"jsonrpc", "method"}, "properties": gin.H{
+				"jsonrpc": gin.H{"type": "string", "enum": []string{jsonRPCVersion}},
+				"method":  gin.H{"type": "string", "description": "action of operation, like subtract, or health"},
+				"params": gin.H{"oneOf": []gin.H{
+					{"type": "object", "properties": gin.H{"x": number, "y": number, "values": gin.H{"type": "array", "items": number},
+						"mode": enumSchema(ops.DivModes, defaultDivMode)}},
+					{"type": "array", "items": number},
+				}},
+				"id": gin.H{"description": "string or number, request without id is a notification"},
+			}},
+			"RPCResponse": gin.H{"type": "object", "properties": gin.H{
+				"jsonrpc": gin.H{"type": "string", "enum": []string{jsonRPCVersion}},
+				"result":  gin.H{"oneOf": []gin.H{schemaRef("Result"), schemaRef("Health")}},
+				"error": gin.H{"type": "object", "properties": gin.H{
+					"code": integer, "message": str, "data": schemaRef("Problem"),
+				}},
+				"id": gin.H{},
+			}},
+		},
+		"responses": gin.H{
+			"Problem": gin.H{
+				"description": "Problem details",
+				"headers":     gin.H{requestIDHeader: gin.H{"$ref": "#/components/headers/RequestID"}},
+				"content":     gin.H{mimeProblemJSON: gin.H{"schema": schemaRef("Problem")}},
+			},
+		},
+		"headers": gin.H{
+			"RequestID": gin.H{"description": "ID of the request, same as request_id of problem details", "schema": str},
+		},
+	}
+}
+
+// docs serve the docs page, it renders /openapi.json without external assets
+func docs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>teltechcc API</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.op { border: 1px solid #ddd; margin: 0.5em 0; padding: 0.5em; }
+.method { font-weight: bold; text-transform: uppercase; display: inline-block; width: 4em; }
+.deprecated { color: #999; text-decoration: line-through; }
+td { padding: 0 1em 0 0; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>teltechcc API</h1>
+<p>Generated from <a href="/openapi.json">/openapi.json</a>.</p>
+<div id="paths"></div>
+<script>
+fetch("/openapi.json").then(function (r) { return r.json(); }).then(function (doc) {
+  var root = document.getElementById("paths");
+  Object.keys(doc.paths).sort().forEach(function (path) {
+    Object.keys(doc.paths[path]).forEach(function (method) {
+      var op = doc.paths[path][method];
+      var div = document.createElement("div");
+      div.className = "op";
+      var title = document.createElement("div");
+      title.innerHTML = "<span class=method></span><code></code> ";
+      title.children[0].textContent = method;
+      title.children[1].textContent = path;
+      if (op.deprecated) { title.children[1].className = "deprecated"; }
+      title.appendChild(document.createTextNode(op.summary || ""));
+      div.appendChild(title);
+      var table = document.createElement("table");
+      (op.parameters || []).forEach(function (p) {
+        var row = table.insertRow();
+        row.insertCell().textContent = p.name + (p.required ? " *" : "");
+        row.insertCell().textContent = p.description || "";
+        row.insertCell().textContent = p.schema.enum ? p.schema.enum.join(", ") : "";
+      });
+      div.appendChild(table);
+      root.appendChild(div);
+    });
+  });
+});
+</script>
+</body>
+</html>
+`
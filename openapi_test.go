@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// getOpenAPI return decoded /openapi.json of router
+func getOpenAPI(t *testing.T) map[string]interface{} {
+	router := newRouter()
+	w := performRequest(router, "GET", "/openapi.json")
+	if w.Code != 200 {
+		t.Fatalf("error on: openapi\ngot code:\n %v \nexp code\n %v \n", w.Code, 200)
+	}
+	var doc map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("error on: openapi\ninvalid json: %v\n", err)
+	}
+	return doc
+}
+
+func TestOpenAPISync(t *testing.T) {
+	setUpLogger(false)
+	serveDocs = true
+	defer func() { serveDocs = false }()
+	doc := getOpenAPI(t)
+	paths := doc["paths"].(map[string]interface{})
+	// every route has its operation with summary, unknown route has no summary
+	routes := newRouter().Routes()
+	for _, route := range routes {
+		item, ok := paths[route.Path].(map[string]interface{})
+		if ok == false {
+			t.Errorf("error on: %v %v\npath is not documented\n", route.Method, route.Path)
+			continue
+		}
+		op, ok := item[strings.ToLower(route.Method)].(map[string]interface{})
+		if ok == false || op["summary"] == nil {
+			t.Errorf("error on: %v %v\nroute is not documented\n", route.Method, route.Path)
+		}
+	}
+	count := 0
+	for _, item := range paths {
+		count += len(item.(map[string]interface{}))
+	}
+	if count != len(routes) {
+		t.Errorf("error on: openapi\ngot operations:\n %v \nexp operations\n %v \n", count, len(routes))
+	}
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	setUpLogger(false)
+	doc := getOpenAPI(t)
+	paths := doc["paths"].(map[string]interface{})
+	params := func(path, method string) []string {
+		op := paths[path].(map[string]interface{})[method].(map[string]interface{})
+		var names []string
+		for _, p := range op["parameters"].([]interface{}) {
+			names = append(names, p.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+	cases := []struct {
+		name, path, method string
+		expParams          string
+		expDeprecated      bool
+	}{
+		{name: "case add", path: "/add", method: "get", expParams: "x y v values precision type scale rounding Cache-Control", expDeprecated: true},
+		{name: "case v2 divide", path: "/v2/divide", method: "get", expParams: "x y v values mode precision type scale rounding Cache-Control"},
+		{name: "case post", path: "/v1/subtract", method: "post", expParams: "precision type scale rounding Cache-Control", expDeprecated: true},
+		{name: "case mod", path: "/v2/mod", method: "get", expParams: "x y v values precision Cache-Control"},
+		{name: "case rational", path: "/v2/rational/multiply", method: "get", expParams: "x y v values scale Cache-Control"},
+		{name: "case eval", path: "/v2/eval", method: "get", expParams: "expr Cache-Control"},
+	}
+	for _, c := range cases {
+		got := strings.Join(params(c.path, c.method), " ")
+		if got != c.expParams {
+			t.Errorf("error on: %v\ngot params:\n %v \nexp params\n %v \n", c.name, got, c.expParams)
+		}
+		op := paths[c.path].(map[string]interface{})[c.method].(map[string]interface{})
+		if (op["deprecated"] == true) != c.expDeprecated {
+			t.Errorf("error on: %v\ngot deprecated:\n %v \nexp deprecated\n %v \n", c.name, op["deprecated"], c.expDeprecated)
+		}
+	}
+}
+
+func TestOpenAPIRefs(t *testing.T) {
+	setUpLogger(false)
+	doc := getOpenAPI(t)
+	components := doc["components"].(map[string]interface{})
+	// every $ref points at an existing component
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+				group, ok := components[parts[0]].(map[string]interface{})
+				if ok == false || group[parts[1]] == nil {
+					t.Errorf("error on: openapi\nunresolved ref %v\n", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func TestDocs(t *testing.T) {
+	setUpLogger(false)
+	w := performRequest(newRouter(), "GET", "/docs")
+	if w.Code != 404 {
+		t.Errorf("error on: docs disabled\ngot code:\n %v \nexp code\n %v \n", w.Code, 404)
+	}
+	serveDocs = true
+	defer func() { serveDocs = false }()
+	w = performRequest(newRouter(), "GET", "/docs")
+	if w.Code != 200 || strings.Contains(w.Body.String(), "/openapi.json") == false {
+		t.Errorf("error on: docs\ngot code:\n %v \nexp code\n %v \n", w.Code, 200)
+	}
+}
@@ -0,0 +1,142 @@
+package ops
+
+import (
+	"math"
+	"math/big"
+)
+
+// builtin operations, registered in the order of routes
+func init() {
+	Register(Operation{
+		Name: "add", Action: "add", Route: "/add", Arity: 2, Variadic: true, Commutative: true,
+		Int: FoldInt(addInt64),
+		Big: FoldBig(func(v1, v2 *big.Int) *big.Int { return new(big.Int).Add(v1, v2) }),
+		Rat: FoldRat(func(v1, v2 *big.Rat) *big.Rat { return new(big.Rat).Add(v1, v2) }),
+	})
+	Register(Operation{
+		Name: "sub", Action: "subtract", Route: "/subtract", Arity: 2, Variadic: true,
+		Int: FoldInt(subInt64),
+		Big: FoldBig(func(v1, v2 *big.Int) *big.Int { return new(big.Int).Sub(v1, v2) }),
+		Rat: FoldRat(func(v1, v2 *big.Rat) *big.Rat { return new(big.Rat).Sub(v1, v2) }),
+	})
+	Register(Operation{
+		Name: "mul", Action: "multiply", Route: "/multiply", Arity: 2, Variadic: true, Commutative: true,
+		Int: FoldInt(mulInt64),
+		Big: FoldBig(func(v1, v2 *big.Int) *big.Int { return new(big.Int).Mul(v1, v2) }),
+		Rat: FoldRat(func(v1, v2 *big.Rat) *big.Rat { return new(big.Rat).Mul(v1, v2) }),
+	})
+	// divide is floor function, see DivideWithMode for other rounding modes.
+	// with more than 2 operands, every step is floored: 7 / 2 / 2 == 3 / 2 == 1
+	Register(Operation{
+		Name: "div", Action: "divide", Route: "/divide", Arity: 2, Variadic: true, Validate: NonZero(1),
+		Int: FoldInt(func(v1, v2 int64) (int64, error) {
+			q, _, err := DivideWithMode(v1, v2, DivFloor)
+			return q, err
+		}),
+		Big: FoldBig(func(v1, v2 *big.Int) *big.Int {
+			q, _ := BigDivideWithMode(v1, v2, DivFloor)
+			return q
+		}),
+		Rat: FoldRat(func(v1, v2 *big.Rat) *big.Rat { return new(big.Rat).Quo(v1, v2) }),
+	})
+	// mod has the sign of divisor: -7 mod 3 = 2
+	Register(Operation{
+		Name: "mod", Action: "mod", Route: "/mod", Arity: 2, Validate: NonZero(1),
+		Int: func(args []int64) (int64, error) { return floorMod(args[0], args[1]), nil },
+		Big: func(args []*big.Int) (*big.Int, error) {
+			_, r := BigDivideWithMode(args[0], args[1], DivFloor)
+			return r, nil
+		},
+	})
+	// rem has the sign of dividend: -7 rem 3 = -1
+	Register(Operation{
+		Name: "rem", Action: "rem", Route: "/rem", Arity: 2, Validate: NonZero(1),
+		Int: func(args []int64) (int64, error) { return args[0] % args[1], nil },
+		Big: func(args []*big.Int) (*big.Int, error) { return new(big.Int).Rem(args[0], args[1]), nil },
+	})
+}
+
+// NonZero return validation rule that operands from index i are not zero,
+// for variadic division every divisor is checked.
+// ErrDivideByZero is returned otherwise
+func NonZero(i int) func(args []*big.Rat) error {
+	return func(args []*big.Rat) error {
+		for _, arg := range args[i:] {
+			if arg.Sign() == 0 {
+				return ErrDivideByZero
+			}
+		}
+		return nil
+	}
+}
+
+// FoldInt turns binary f into Int of variadic operation.
+// operands are folded from left to right, first error is returned
+func FoldInt(f func(v1, v2 int64) (int64, error)) func(args []int64) (int64, error) {
+	return func(args []int64) (int64, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			var err error
+			result, err = f(result, arg)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// FoldBig is the big.Int version of FoldInt
+func FoldBig(f func(v1, v2 *big.Int) *big.Int) func(args []*big.Int) (*big.Int, error) {
+	return func(args []*big.Int) (*big.Int, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = f(result, arg)
+		}
+		return result, nil
+	}
+}
+
+// FoldRat is the big.Rat version of FoldInt
+func FoldRat(f func(v1, v2 *big.Rat) *big.Rat) func(args []*big.Rat) (*big.Rat, error) {
+	return func(args []*big.Rat) (*big.Rat, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = f(result, arg)
+		}
+		return result, nil
+	}
+}
+
+// addInt64 return v1 + v2 or ErrOverflow
+func addInt64(v1, v2 int64) (int64, error) {
+	if (v2 > 0 && v1 > math.MaxInt64-v2) || (v2 < 0 && v1 < math.MinInt64-v2) {
+		return 0, ErrOverflow
+	}
+	return v1 + v2, nil
+}
+
+// subInt64 return v1 - v2 or ErrOverflow
+func subInt64(v1, v2 int64) (int64, error) {
+	if (v2 < 0 && v1 > math.MaxInt64+v2) || (v2 > 0 && v1 < math.MinInt64+v2) {
+		return 0, ErrOverflow
+	}
+	return v1 - v2, nil
+}
+
+// mulInt64 return v1 * v2 or ErrOverflow
+// MinInt64 * -1 has to be checked separately since the division check
+// below will wrap as well
+func mulInt64(v1, v2 int64) (int64, error) {
+	if v1 == 0 || v2 == 0 {
+		return 0, nil
+	}
+	if (v1 == -1 && v2 == math.MinInt64) || (v2 == -1 && v1 == math.MinInt64) {
+		return 0, ErrOverflow
+	}
+	result := v1 * v2
+	if result/v2 != v1 {
+		return 0, ErrOverflow
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package ops
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// rounding modes of integer division
+const (
+	DivTrunc    = "trunc"
+	DivFloor    = "floor"
+	DivCeil     = "ceil"
+	DivHalfEven = "half-even"
+	DivEuclid   = "euclid"
+)
+
+// DivModes are all supported rounding modes of integer division
+var DivModes = []string{DivTrunc, DivFloor, DivCeil, DivHalfEven, DivEuclid}
+
+// DivideWithMode return quotient rounded with mode and the matching remainder,
+// so that v1 == q * v2 + r always holds.
+// v2 is expected to be non-zero.
+// ErrOverflow will be returned for MinInt64 / -1 regardless of mode
+func DivideWithMode(v1, v2 int64, mode string) (int64, int64, error) {
+	if v1 == math.MinInt64 && v2 == -1 {
+		return 0, 0, ErrOverflow
+	}
+	q, r := v1/v2, v1%v2
+	if r == 0 {
+		return q, r, nil
+	}
+	// r != 0 means |v2| >= 2, so |q| <= MaxInt64 / 2 and q +- 1 won't overflow.
+	// r -+ v2 might overflow in intermediate result when v2 is MinInt64,
+	// but int64 arithmetic wraps and the final remainder is always within range
+	step := int64(1)
+	if (v1 < 0) != (v2 < 0) {
+		step = -1
+	}
+	away := false
+	switch mode {
+	case DivTrunc:
+	case DivFloor:
+		away = step < 0
+	case DivCeil:
+		away = step > 0
+	case DivEuclid:
+		// remainder has to be non-negative
+		away = r < 0
+	case DivHalfEven:
+		// compare 2 * |r| with |v2| in uint64 so that MinInt64 doesn't overflow
+		twice, abs := 2*absUint64(r), absUint64(v2)
+		away = twice > abs || (twice == abs && q%2 != 0)
+	default:
+		panic(fmt.Sprintf("invalid division mode %v", mode))
+	}
+	if away {
+		q += step
+		r -= step * v2
+	}
+	return q, r, nil
+}
+
+func absUint64(v int64) uint64 {
+	if v == math.MinInt64 {
+		return 1 << 63
+	}
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
+}
+
+// BigDivideWithMode is the big.Int version of DivideWithMode
+func BigDivideWithMode(v1, v2 *big.Int, mode string) (*big.Int, *big.Int) {
+	q, r := new(big.Int).QuoRem(v1, v2, new(big.Int))
+	if r.Sign() == 0 {
+		return q, r
+	}
+	step := int64(v1.Sign() * v2.Sign())
+	away := false
+	switch mode {
+	case DivTrunc:
+	case DivFloor:
+		away = step < 0
+	case DivCeil:
+		away = step > 0
+	case DivEuclid:
+		away = r.Sign() < 0
+	case DivHalfEven:
+		twice := new(big.Int).Abs(r)
+		twice.Lsh(twice, 1)
+		cmp := twice.Cmp(new(big.Int).Abs(v2))
+		away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	default:
+		panic(fmt.Sprintf("invalid division mode %v", mode))
+	}
+	if away {
+		q.Add(q, big.NewInt(step))
+		r.Sub(r, new(big.Int).Mul(big.NewInt(step), v2))
+	}
+	return q, r
+}
+
+// floorMod return v1 mod v2, result has the same sign as v2
+func floorMod(v1, v2 int64) int64 {
+	r := v1 % v2
+	if r != 0 && (r < 0) != (v2 < 0) {
+		r += v2
+	}
+	return r
+}
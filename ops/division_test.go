@@ -0,0 +1,81 @@
+package ops
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestDivideWithMode(t *testing.T) {
+	cases := []struct {
+		name, mode string
+		v1, v2     int64
+		expQ, expR int64
+		expErr     error
+	}{
+		{name: "case trunc", mode: DivTrunc, v1: -7, v2: 2, expQ: -3, expR: -1},
+		{name: "case floor", mode: DivFloor, v1: -7, v2: 2, expQ: -4, expR: 1},
+		{name: "case floor positive", mode: DivFloor, v1: 7, v2: 2, expQ: 3, expR: 1},
+		{name: "case floor -1/2", mode: DivFloor, v1: -1, v2: 2, expQ: -1, expR: 1},
+		{name: "case ceil", mode: DivCeil, v1: 7, v2: 2, expQ: 4, expR: -1},
+		{name: "case ceil negative", mode: DivCeil, v1: -7, v2: 2, expQ: -3, expR: -1},
+		{name: "case euclid", mode: DivEuclid, v1: -7, v2: 2, expQ: -4, expR: 1},
+		{name: "case euclid negative divisor", mode: DivEuclid, v1: -7, v2: -2, expQ: 4, expR: 1},
+		{name: "case euclid positive", mode: DivEuclid, v1: 7, v2: -2, expQ: -3, expR: 1},
+		{name: "case half-even down", mode: DivHalfEven, v1: 5, v2: 2, expQ: 2, expR: 1},
+		{name: "case half-even up", mode: DivHalfEven, v1: 7, v2: 2, expQ: 4, expR: -1},
+		{name: "case half-even negative", mode: DivHalfEven, v1: -7, v2: 2, expQ: -4, expR: 1},
+		{name: "case half-even below half", mode: DivHalfEven, v1: 4, v2: 3, expQ: 1, expR: 1},
+		{name: "case half-even above half", mode: DivHalfEven, v1: 5, v2: 3, expQ: 2, expR: -1},
+		{name: "case exact", mode: DivCeil, v1: 6, v2: 3, expQ: 2, expR: 0},
+		{name: "case min / -1", mode: DivFloor, v1: math.MinInt64, v2: -1, expErr: ErrOverflow},
+		{name: "case max ceil", mode: DivCeil, v1: math.MaxInt64, v2: 2, expQ: 1 << 62, expR: -1},
+		{name: "case min divisor", mode: DivHalfEven, v1: math.MaxInt64, v2: math.MinInt64, expQ: -1, expR: -1},
+		{name: "case min divisor floor", mode: DivFloor, v1: 1, v2: math.MinInt64, expQ: -1, expR: math.MinInt64 + 1},
+	}
+	for _, c := range cases {
+		gotQ, gotR, gotErr := DivideWithMode(c.v1, c.v2, c.mode)
+		if gotQ != c.expQ || gotR != c.expR {
+			t.Errorf("error on: %v\ngot q, r:\n %v %v \nexp q, r\n %v %v \n", c.name, gotQ, gotR, c.expQ, c.expR)
+		}
+		if gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+	}
+}
+
+func TestBigDivideWithMode(t *testing.T) {
+	// big version should agree with int64 version
+	modes := []string{DivTrunc, DivFloor, DivCeil, DivHalfEven, DivEuclid}
+	values := []int64{-9, -7, -4, -3, -2, -1, 1, 2, 3, 4, 7, 9}
+	for _, mode := range modes {
+		for _, v1 := range values {
+			for _, v2 := range values {
+				expQ, expR, _ := DivideWithMode(v1, v2, mode)
+				gotQ, gotR := BigDivideWithMode(big.NewInt(v1), big.NewInt(v2), mode)
+				if gotQ.Int64() != expQ || gotR.Int64() != expR {
+					t.Errorf("error on: %v %v / %v\ngot q, r:\n %v %v \nexp q, r\n %v %v \n", mode, v1, v2, gotQ, gotR, expQ, expR)
+				}
+			}
+		}
+	}
+}
+
+func TestFloorMod(t *testing.T) {
+	cases := []struct {
+		name             string
+		v1, v2, expected int64
+	}{
+		{name: "case 1", v1: 7, v2: 3, expected: 1},
+		{name: "case 2", v1: -7, v2: 3, expected: 2},
+		{name: "case 3", v1: 7, v2: -3, expected: -2},
+		{name: "case 4", v1: -7, v2: -3, expected: -1},
+		{name: "case 5", v1: math.MinInt64, v2: -1, expected: 0},
+	}
+	for _, c := range cases {
+		got := floorMod(c.v1, c.v2)
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package ops
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// ErrOverflow is returned by Int compute function if result is beyond int64 range
+var ErrOverflow = fmt.Errorf("Integer overflow. Result is out of range %d through %d", int64(math.MinInt64), int64(math.MaxInt64))
+
+// ErrDivideByZero is returned by Validate if divisor is zero
+var ErrDivideByZero = fmt.Errorf("Divide by zero")
+
+// Operation describes a calculation served by the server.
+// Registering an Operation is all it takes to add a new endpoint,
+// route, cache key and validation are derived from it.
+type Operation struct {
+	// Name is the prefix of cache key, like `sub`. It has to be unique
+	Name string
+	// Action is returned in response, like `subtract`
+	Action string
+	// Route is the path of endpoint, like `/subtract`
+	Route string
+	// Arity is the number of operands, at least 1.
+	// x and y of endpoints are accepted only if it is 2, operands are a list of values otherwise
+	Arity int
+	// Variadic operation accepts Arity or more operands,
+	// they are folded from left to right: 1 - 2 - 3 == (1 - 2) - 3
+	Variadic bool
+	// Commutative operation has operands sorted in cache key,
+	// so that 1 + 2 and 2 + 1 share the same key
+	Commutative bool
+	// Validate is optional, it checks all operands after they are parsed.
+	// operands of every numeric type are converted to big.Rat losslessly
+	Validate func(args []*big.Rat) error
+	// Int do the calculation in int64, ErrOverflow should be returned if result is out of range.
+	// len(args) is Arity unless operation is Variadic
+	Int func(args []int64) (int64, error)
+	// Big is optional, arbitrary precision version of Int
+	Big func(args []*big.Int) (*big.Int, error)
+	// Rat is optional, exact rational version of Int.
+	// decimal results are rounded from it as well
+	Rat func(args []*big.Rat) (*big.Rat, error)
+}
+
+var (
+	mutex    = &sync.RWMutex{}
+	registry = make(map[string]Operation)
+	// keep the order of registration so that routes are registered in the same order
+	names []string
+)
+
+// Register add op to registry.
+// It panics if op is invalid or name/route is already registered,
+// same as http.Handle, it is supposed to be called in init
+func Register(op Operation) {
+	if op.Name == "" || strings.Contains(op.Name, ":") {
+		panic(fmt.Sprintf("ops: invalid operation name %q", op.Name))
+	}
+	if strings.HasPrefix(op.Route, "/") == false {
+		panic(fmt.Sprintf("ops: route of %v has to start with /", op.Name))
+	}
+	if op.Arity < 1 {
+		panic(fmt.Sprintf("ops: arity of %v has to be at least 1, got %v", op.Name, op.Arity))
+	}
+	if op.Int == nil {
+		panic(fmt.Sprintf("ops: Int of %v is nil", op.Name))
+	}
+	if op.Action == "" {
+		op.Action = op.Name
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, ok := registry[op.Name]; ok {
+		panic(fmt.Sprintf("ops: operation %v registered twice", op.Name))
+	}
+	for _, exist := range registry {
+		if exist.Route == op.Route {
+			panic(fmt.Sprintf("ops: route %v of %v is registered by %v", op.Route, op.Name, exist.Name))
+		}
+	}
+	registry[op.Name] = op
+	names = append(names, op.Name)
+}
+
+// Get return operation by name
+func Get(name string) (Operation, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	op, ok := registry[name]
+	return op, ok
+}
+
+// All return all operations in order of registration
+func All() []Operation {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	all := make([]Operation, 0, len(names))
+	for _, name := range names {
+		all = append(all, registry[name])
+	}
+	return all
+}
@@ -0,0 +1,127 @@
+package ops
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func intFirst(args []int64) (int64, error) {
+	return args[0], nil
+}
+
+func TestRegister(t *testing.T) {
+	cases := []struct {
+		name     string
+		op       Operation
+		expPanic bool
+	}{
+		{name: "case valid", op: Operation{Name: "first", Route: "/first", Arity: 2, Int: intFirst}, expPanic: false},
+		{name: "case unary", op: Operation{Name: "neg", Route: "/neg", Arity: 1, Int: intFirst}, expPanic: false},
+		{name: "case empty name", op: Operation{Route: "/empty", Arity: 2, Int: intFirst}, expPanic: true},
+		{name: "case name with colon", op: Operation{Name: "a:b", Route: "/ab", Arity: 2, Int: intFirst}, expPanic: true},
+		{name: "case invalid route", op: Operation{Name: "route", Route: "route", Arity: 2, Int: intFirst}, expPanic: true},
+		{name: "case no arity", op: Operation{Name: "none", Route: "/none", Int: intFirst}, expPanic: true},
+		{name: "case nil Int", op: Operation{Name: "nil", Route: "/nil", Arity: 2}, expPanic: true},
+		{name: "case duplicated name", op: Operation{Name: "add", Route: "/add2", Arity: 2, Int: intFirst}, expPanic: true},
+		{name: "case duplicated route", op: Operation{Name: "plus", Route: "/add", Arity: 2, Int: intFirst}, expPanic: true},
+	}
+	for _, c := range cases {
+		gotPanic := func() (panicked bool) {
+			defer func() {
+				panicked = recover() != nil
+			}()
+			Register(c.op)
+			return false
+		}()
+		if gotPanic != c.expPanic {
+			t.Errorf("error on: %v\ngot panic:\n %v \nexp panic\n %v \n", c.name, gotPanic, c.expPanic)
+		}
+	}
+
+	op, ok := Get("first")
+	if ok == false {
+		t.Fatalf("registered operation not found")
+	}
+	if op.Action != "first" {
+		t.Errorf("error on: default action\ngot:\n %v \nexp\n %v \n", op.Action, "first")
+	}
+	all := All()
+	if all[len(all)-2].Name != "first" || all[len(all)-1].Name != "neg" {
+		t.Errorf("error on: order of All\ngot:\n %v %v \nexp\n %v %v \n", all[len(all)-2].Name, all[len(all)-1].Name, "first", "neg")
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	cases := []struct {
+		name, op string
+		v1, v2   int64
+		expected int64
+		expErr   error
+	}{
+		{name: "case add", op: "add", v1: 1, v2: 2, expected: 3},
+		{name: "case sub", op: "sub", v1: 1, v2: 2, expected: -1},
+		{name: "case mul", op: "mul", v1: 3, v2: -2, expected: -6},
+		{name: "case div", op: "div", v1: -7, v2: 2, expected: -4},
+		{name: "case mod", op: "mod", v1: -7, v2: 3, expected: 2},
+		{name: "case rem", op: "rem", v1: -7, v2: 3, expected: -1},
+		{name: "case add overflow", op: "add", v1: math.MaxInt64, v2: 1, expErr: ErrOverflow},
+	}
+	for _, c := range cases {
+		op, ok := Get(c.op)
+		if ok == false {
+			t.Errorf("error on: %v\noperation %v not registered\n", c.name, c.op)
+			continue
+		}
+		got, gotErr := op.Int([]int64{c.v1, c.v2})
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+		}
+		if gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+	}
+}
+
+func TestNonZero(t *testing.T) {
+	validate := NonZero(1)
+	cases := []struct {
+		name   string
+		args   []*big.Rat
+		expErr error
+	}{
+		{name: "case non zero", args: []*big.Rat{big.NewRat(1, 1), big.NewRat(1, 2)}, expErr: nil},
+		{name: "case zero", args: []*big.Rat{big.NewRat(1, 1), big.NewRat(0, 1)}, expErr: ErrDivideByZero},
+		{name: "case zero dividend", args: []*big.Rat{big.NewRat(0, 1), big.NewRat(1, 1)}, expErr: nil},
+		{name: "case last zero", args: []*big.Rat{big.NewRat(1, 1), big.NewRat(2, 1), big.NewRat(0, 1)}, expErr: ErrDivideByZero},
+	}
+	for _, c := range cases {
+		gotErr := validate(c.args)
+		if gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+	}
+}
+
+func TestFoldInt(t *testing.T) {
+	sub := FoldInt(subInt64)
+	cases := []struct {
+		name     string
+		args     []int64
+		expected int64
+		expErr   error
+	}{
+		{name: "case binary", args: []int64{3, 1}, expected: 2},
+		{name: "case left to right", args: []int64{10, 3, 2}, expected: 5},
+		{name: "case overflow", args: []int64{math.MinInt64, 1, -5}, expErr: ErrOverflow},
+	}
+	for _, c := range cases {
+		got, gotErr := sub(c.args)
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+		}
+		if gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+	}
+}
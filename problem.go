@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"runtime/debug"
+)
+
+// error codes are stable and machine readable,
+// clients should match code instead of detail, which is for human and may change
+const (
+	codeMissingOperand   = "missing_operand"
+	codeInvalidType      = "invalid_type"
+	codeOperandCount     = "operand_count"
+	codeInvalidParameter = "invalid_parameter"
+	codeUnsupported      = "unsupported"
+	codeInvalidBody      = "invalid_body"
+	codeInvalidExpr      = "invalid_expression"
+	codeDivideByZero     = "divide_by_zero"
+	codeOverflow         = "overflow"
+	codeCalculation      = "calculation"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeNotAcceptable    = "not_acceptable"
+	codeRateLimited      = "rate_limited"
+	codeInternal         = "internal"
+)
+
+// problemTypePrefix is the prefix of problem type, followed by code
+const problemTypePrefix = "urn:teltechcc:problem:"
+
+const (
+	mimeProblemJSON = "application/problem+json"
+	mimeProblemXML  = "application/problem+xml"
+)
+
+// problemTypes are status and title of each code
+var problemTypes = map[string]struct {
+	status int
+	title  string
+}{
+	codeMissingOperand:   {400, "Missing operand"},
+	codeInvalidType:      {400, "Invalid operand type"},
+	codeOperandCount:     {400, "Invalid number of operands"},
+	codeInvalidParameter: {400, "Invalid parameter"},
+	codeUnsupported:      {400, "Unsupported operation"},
+	codeInvalidBody:      {400, "Invalid request body"},
+	codeInvalidExpr:      {400, "Invalid expression"},
+	codeDivideByZero:     {400, "Divide by zero"},
+	codeOverflow:         {422, "Integer overflow"},
+	codeCalculation:      {422, "Calculation failed"},
+	codeNotFound:         {404, "Not found"},
+	codeMethodNotAllowed: {405, "Method not allowed"},
+	codeNotAcceptable:    {406, "Not acceptable"},
+	codeRateLimited:      {429, "Too many requests"},
+	codeInternal:         {500, "Internal server error"},
+}
+
+var errNotFound = fmt.Errorf("Route not found")
+var errMethodNotAllowed = fmt.Errorf("Method not allowed")
+var errInternal = fmt.Errorf("Internal server error")
+
+// errCodes maps errors to code, wrapped errors are matched as well
+var errCodes = map[error]string{
+	errMissX:            codeMissingOperand,
+	errMissY:            codeMissingOperand,
+	errMissExpr:         codeMissingOperand,
+	errMissOp:           codeMissingOperand,
+	errType:             codeInvalidType,
+	errBigType:          codeInvalidType,
+	errDecimalType:      codeInvalidType,
+	errRationalType:     codeInvalidType,
+	errOperands:         codeOperandCount,
+	errDivMode:          codeInvalidParameter,
+	errScale:            codeInvalidParameter,
+	errRounding:         codeInvalidParameter,
+	errPrecision:        codeInvalidParameter,
+	errNumType:          codeInvalidParameter,
+	errWSHandshake:      codeInvalidParameter,
+	errMaxAge:           codeInvalidParameter,
+	errUnsupportedType:  codeUnsupported,
+	errUnsupportedOp:    codeUnsupported,
+	errBody:             codeInvalidBody,
+	errBatchBody:        codeInvalidBody,
+	errBatchSize:        codeInvalidBody,
+	errBatchItem:        codeInvalidBody,
+	errRPCParse:         codeInvalidBody,
+	errRPCRequest:       codeInvalidBody,
+	errRPCParams:        codeInvalidBody,
+	errWSMessage:        codeInvalidBody,
+	errExprLength:       codeInvalidExpr,
+	errDivideByZero:     codeDivideByZero,
+	errOverflow:         codeOverflow,
+	errNotFound:         codeNotFound,
+	errMethodNotAllowed: codeMethodNotAllowed,
+	errNotAcceptable:    codeNotAcceptable,
+	errRateLimited:      codeRateLimited,
+	errInternal:         codeInternal,
+}
+
+// errorCode return code of err, or fallback if err is unknown
+func errorCode(err error, fallback string) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if code, ok := errCodes[err]; ok {
+			return code
+		}
+	}
+	return fallback
+}
+
+// fieldError is an error of a query string, body field or header.
+// value is the rejected one, empty if field is missing
+type fieldError struct {
+	field, value string
+	err          error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// operandError is an error of the i-th operand.
+// it is renamed to fieldError by namedOperand once the handler knows how operands are provided
+type operandError struct {
+	index int
+	value string
+	err   error
+}
+
+func (e *operandError) Error() string {
+	return e.err.Error()
+}
+
+func (e *operandError) Unwrap() error {
+	return e.err
+}
+
+// namedOperand convert operandError to fieldError of x, y or values[i], same as operandFields
+func namedOperand(err error, list bool) error {
+	e, ok := err.(*operandError)
+	if ok == false {
+		return err
+	}
+	field := fmt.Sprintf("values[%d]", e.index)
+	if list == false && e.index < 2 {
+		field = []string{"x", "y"}[e.index]
+	}
+	return &fieldError{field: field, value: e.value, err: e.err}
+}
+
+// problem is an error response in RFC 7807 problem details
+type problem struct {
+	code         string
+	err          error
+	field, value string
+	// ext are extension members, like pos of /eval
+	ext gin.H
+}
+
+// problemOf return problem of err, fallback is the code if err is unknown
+func problemOf(err error, fallback string) *problem {
+	p := &problem{code: errorCode(err, fallback), err: err}
+	var e *fieldError
+	if errors.As(err, &e) {
+		p.field, p.value = e.field, e.value
+	}
+	return p
+}
+
+func (p *problem) status() int {
+	return problemTypes[p.code].status
+}
+
+// body return problem details.
+// instance and requestID are omitted if empty, like problem of batch item
+func (p *problem) body(instance, requestID string) gin.H {
+	t := problemTypes[p.code]
+	h := gin.H{
+		"type":   problemTypePrefix + p.code,
+		"title":  t.title,
+		"status": t.status,
+		"detail": p.err.Error(),
+		"code":   p.code,
+	}
+	if instance != "" {
+		h["instance"] = instance
+	}
+	if requestID != "" {
+		h["request_id"] = requestID
+	}
+	if p.field != "" {
+		h["field"] = p.field
+	}
+	if p.value != "" {
+		h["value"] = p.value
+	}
+	for k, v := range p.ext {
+		h[k] = v
+	}
+	return h
+}
+
+// respondError respond err as problem, unknown error is internal server error
+func respondError(ctx *gin.Context, err error) {
+	respondProblem(ctx, problemOf(err, codeInternal))
+}
+
+// respondProblem render p in negotiated format.
+// JSON and XML are application/problem+json and application/problem+xml,
+// others share the schema of normal response
+func respondProblem(ctx *gin.Context, p *problem) {
+	h := p.body(ctx.Request.URL.RequestURI(), ctx.GetString(requestIDKey))
+	switch ctx.GetString(formatKey) {
+	case mimeXML:
+		ctx.Header("Content-Type", mimeProblemXML+"; charset=utf-8")
+		ctx.XML(p.status(), xmlProblem(h))
+	case mimeYAML, mimeMsgPack, mimeProtoBuf:
+		respond(ctx, p.status(), h)
+	default:
+		ctx.Header("Content-Type", mimeProblemJSON)
+		ctx.JSON(p.status(), h)
+	}
+}
+
+// xmlProblem render problem details as `<problem xmlns="urn:ietf:rfc:7807">`
+type xmlProblem gin.H
+
+func (h xmlProblem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Space: "urn:ietf:rfc:7807", Local: "problem"}}
+	return marshalSorted(e, start, gin.H(h))
+}
+
+// requestIDKey is the key of request ID in gin.Context
+const requestIDKey = "request_id"
+
+const requestIDHeader = "X-Request-Id"
+
+// validRequestID is the X-Request-Id accepted from client, others are replaced
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// newRequestID return random request ID, it is replaced in tests
+var newRequestID = func() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID is the middleware setting request ID, from X-Request-Id if valid.
+// it is returned in X-Request-Id header and problem details
+func requestID(ctx *gin.Context) {
+	id := ctx.GetHeader(requestIDHeader)
+	if validRequestID.MatchString(id) == false {
+		id = newRequestID()
+	}
+	ctx.Set(requestIDKey, id)
+	ctx.Header(requestIDHeader, id)
+	ctx.Next()
+}
+
+// recovery is the middleware responding 500 problem if handler panics
+func recovery(ctx *gin.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			Error.Printf("panic on %v %v, request %v: %v\n%s", ctx.Request.Method, ctx.Request.URL.Path,
+				ctx.GetString(requestIDKey), r, debug.Stack())
+			if ctx.Writer.Written() == false {
+				respondProblem(ctx, problemOf(errInternal, codeInternal))
+			}
+			ctx.Abort()
+		}
+	}()
+	ctx.Next()
+}
+
+// notFound is the handler of unknown route
+func notFound(ctx *gin.Context) {
+	acceptFormat(ctx)
+	respondProblem(ctx, problemOf(&fieldError{field: "path", value: ctx.Request.URL.Path, err: errNotFound}, codeNotFound))
+}
+
+// methodNotAllowed is the handler of known route with unknown method
+func methodNotAllowed(ctx *gin.Context) {
+	acceptFormat(ctx)
+	respondProblem(ctx, problemOf(&fieldError{field: "method", value: ctx.Request.Method, err: errMethodNotAllowed}, codeMethodNotAllowed))
+}
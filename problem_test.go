@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "case miss x", err: errMissX, expected: codeMissingOperand},
+		{name: "case field", err: &fieldError{field: "y", err: errMissY}, expected: codeMissingOperand},
+		{name: "case operand", err: &operandError{index: 1, value: "a", err: errDecimalType}, expected: codeInvalidType},
+		{name: "case operand count", err: errOperandCount(getOp("mod")), expected: codeOperandCount},
+		{name: "case unsupported", err: errUnsupported(getOp("mod"), typeDecimal), expected: codeUnsupported},
+		{name: "case unknown op", err: errUnknownOp("pow"), expected: codeUnsupported},
+		{name: "case scale", err: errScale, expected: codeInvalidParameter},
+		{name: "case divide by zero", err: errDivideByZero, expected: codeDivideByZero},
+		{name: "case overflow", err: errOverflow, expected: codeOverflow},
+		{name: "case unknown", err: fmt.Errorf("unknown"), expected: "fallback"},
+	}
+	for _, c := range cases {
+		got := errorCode(c.err, "fallback")
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+		}
+	}
+	// every code has status and title
+	for err, code := range errCodes {
+		if problemTypes[code].status == 0 || problemTypes[code].title == "" {
+			t.Errorf("error on: %v\nno problem type of code %v\n", err, code)
+		}
+	}
+}
+
+func TestNamedOperand(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		list     bool
+		expected error
+	}{
+		{name: "case x", err: &operandError{index: 0, value: "a", err: errType},
+			expected: &fieldError{field: "x", value: "a", err: errType}},
+		{name: "case y", err: &operandError{index: 1, value: "0", err: errDivideByZero},
+			expected: &fieldError{field: "y", value: "0", err: errDivideByZero}},
+		{name: "case values", err: &operandError{index: 1, value: "a", err: errType}, list: true,
+			expected: &fieldError{field: "values[1]", value: "a", err: errType}},
+		{name: "case not operand", err: errMissX, expected: errMissX},
+	}
+	for _, c := range cases {
+		got := namedOperand(c.err, c.list)
+		if reflect.DeepEqual(got, c.expected) == false {
+			t.Errorf("error on: %v\ngot:\n %v \nexp\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestRuleValidationOperand(t *testing.T) {
+	_, err := opValidation(getOp("div"), []string{"8", "0", "4", "0"})
+	e, ok := err.(*operandError)
+	if ok == false || e.index != 1 || e.value != "0" || e.err != errDivideByZero {
+		t.Errorf("error on: rule validation\ngot:\n %#v \nexp\n index 1 of divide by zero \n", err)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	setUpLogger(false)
+	cache = NewFakeCache()
+	router := newRouter()
+	cases := []struct {
+		name, id, expected string
+	}{
+		{name: "case generated", id: "", expected: testRequestID},
+		{name: "case from client", id: "abc-123", expected: "abc-123"},
+		{name: "case invalid", id: "a b", expected: testRequestID},
+	}
+	for _, c := range cases {
+		w := performRequestWithHeader(router, "GET", "/add?x=1", requestIDHeader, c.id)
+		if got := w.Header().Get(requestIDHeader); got != c.expected {
+			t.Errorf("error on: %v\ngot header:\n %v \nexp header\n %v \n", c.name, got, c.expected)
+		}
+		var body gin.H
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body["request_id"] != c.expected {
+			t.Errorf("error on: %v\ngot request id:\n %v \nexp request id\n %v \n", c.name, body["request_id"], c.expected)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	setUpLogger(false)
+	router := newRouter()
+	router.GET("/panic", func(ctx *gin.Context) { panic("boom") })
+	w := performRequest(router, "GET", "/panic")
+	if w.Code != 500 {
+		t.Errorf("error on: recovery\ngot code:\n %v \nexp code\n %v \n", w.Code, 500)
+	}
+	jsonEncoded, _ := json.Marshal(problemBody("/panic", codeInternal, errInternal, "", ""))
+	if w.Body.String() != string(jsonEncoded) {
+		t.Errorf("error on: recovery\ngot body:\n %v \nexp body\n %v \n", w.Body.String(), string(jsonEncoded))
+	}
+}
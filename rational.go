@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math/big"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const typeRational = "rational"
+
+// fraction like `-1/3` or decimal like `0.25`.
+// exponent is not accepted so that `1e1000000` won't exhaust memory
+var rationalRegex = regexp.MustCompile(`^[+-]?([0-9]+(/[0-9]+)?|[0-9]+\.[0-9]*|\.[0-9]+)$`)
+
+// parseRational parse s as fraction or decimal number.
+// returned value is always reduced, 2/4 will be 1/2
+func parseRational(s string) (*big.Rat, bool) {
+	if rationalRegex.MatchString(s) == false {
+		return nil, false
+	}
+	// SetString returns false if denominator is zero
+	return new(big.Rat).SetString(s)
+}
+
+// calculateRational do the calculation with Rat of the registered operation.
+// caller should make sure Rat is not nil
+func calculateRational(f string, values ...*big.Rat) (*big.Rat, error) {
+	return getOp(f).Rat(values)
+}
+
+// genRationalCacheKey generate key of cache in format of `rational:func:v1:v2:...`.
+// values are reduced fraction, so that 2/4 and 1/2 share the same key.
+// same as genCacheKey, values will be sorted for commutative operation.
+func genRationalCacheKey(f string, values ...*big.Rat) string {
+	if getOp(f).Commutative {
+		values = append([]*big.Rat(nil), values...)
+		sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	}
+	keys := make([]interface{}, len(values))
+	for i, v := range values {
+		keys[i] = v.String()
+	}
+	return genUnSortedCacheKey(typeRational+":"+f, keys...)
+}
+
+// getRationalResult is the rational version of getResult.
+// value is cached as reduced fraction, like `1/2`
+func getRationalResult(ttl time.Duration, f string, values ...*big.Rat) (*big.Rat, bool, error) {
+	cacheKey := genRationalCacheKey(f, values...)
+	e := newCalcEvent(f, typeRational, len(values), func(i int) interface{} { return values[i] })
+	val, cached, err := cachedResult(cacheKey, ttl, e, func() (string, error) {
+		result, err := calculateRational(f, values...)
+		if err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	result, ok := new(big.Rat).SetString(val)
+	if ok == false {
+		Warning.Printf("invalid cached value %v of key %v\n", val, cacheKey)
+		result, err = calculateRational(f, values...)
+		return result, false, err
+	}
+	return result, cached, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func mustRat(s string) *big.Rat {
+	r, ok := parseRational(s)
+	if ok == false {
+		panic("invalid rational " + s)
+	}
+	return r
+}
+
+func TestParseRational(t *testing.T) {
+	cases := []struct {
+		name, s, expected string
+		expBool           bool
+	}{
+		{name: "case int", s: "3", expected: "3/1", expBool: true},
+		{name: "case fraction", s: "1/3", expected: "1/3", expBool: true},
+		{name: "case reduced", s: "-2/4", expected: "-1/2", expBool: true},
+		{name: "case decimal", s: "0.25", expected: "1/4", expBool: true},
+		{name: "case leading dot", s: "-.5", expected: "-1/2", expBool: true},
+		{name: "case zero denominator", s: "1/0", expBool: false},
+		{name: "case negative denominator", s: "1/-3", expBool: false},
+		{name: "case exponent", s: "1e1000000", expBool: false},
+		{name: "case hex", s: "0x10/3", expBool: false},
+		{name: "case empty", s: "", expBool: false},
+	}
+	for _, c := range cases {
+		got, gotBool := parseRational(c.s)
+		if gotBool != c.expBool {
+			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
+		}
+		if gotBool && got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestCalculateRational(t *testing.T) {
+	cases := []struct {
+		name, f, v1, v2, expected string
+	}{
+		{name: "case add", f: "add", v1: "1/3", v2: "1/6", expected: "1/2"},
+		{name: "case sub", f: "sub", v1: "1/3", v2: "1/2", expected: "-1/6"},
+		{name: "case mul", f: "mul", v1: "2/3", v2: "0.75", expected: "1/2"},
+		{name: "case div", f: "div", v1: "1", v2: "3", expected: "1/3"},
+		{name: "case div negative", f: "div", v1: "-1/2", v2: "1/4", expected: "-2/1"},
+	}
+	for _, c := range cases {
+		got, _ := calculateRational(c.f, mustRat(c.v1), mustRat(c.v2))
+		if got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestGenRationalCacheKey(t *testing.T) {
+	cases := []struct {
+		name, f, v1, v2, expected string
+	}{
+		{name: "case reduced", f: "add", v1: "2/4", v2: "1/3", expected: "rational:add:1/3:1/2"},
+		{name: "case decimal", f: "mul", v1: "0.5", v2: "1/3", expected: "rational:mul:1/3:1/2"},
+		{name: "case not sorted", f: "sub", v1: "2/4", v2: "1/3", expected: "rational:sub:1/2:1/3"},
+	}
+	for _, c := range cases {
+		got := genRationalCacheKey(c.f, mustRat(c.v1), mustRat(c.v2))
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestGetRationalResult(t *testing.T) {
+	cases := []struct {
+		name, f, x, y, expected string
+		expBool                 bool
+		fCache                  *fakeCacheClient
+	}{
+		{
+			name: "case 1", f: "add", x: "1/3", y: "1/6", expected: "1/2",
+			fCache: NewFakeCache(), expBool: false,
+		},
+		{
+			name: "case 2", f: "add", x: "2/6", y: "1/6", expected: "1/2",
+			fCache: &fakeCacheClient{val: map[string]string{"rational:add:1/6:1/3": "1/2"}}, expBool: true,
+		},
+	}
+	for _, c := range cases {
+		cache = c.fCache
+
+		got, gotBool, _ := getRationalResult(time.Minute, c.f, mustRat(c.x), mustRat(c.y))
+		if got.String() != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+		if gotBool != c.expBool {
+			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/ThisisYang/teltechcc/expr"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const replPrompt = "> "
+
+const replHelp = `  <op> <operand>...  calculate, like add 2 5 or subtract 10 3 2
+  <expression>       evaluate expression, like (2+3)*4
+  !<n>               run n-th command of :history again
+  :history           list commands
+  :stats             cache hit and size
+  :flush             flush cache
+  :help              this message
+  :quit              exit, same as EOF`
+
+var errHistory = fmt.Errorf("No such command in history")
+
+// repl is an interactive shell calculating locally, see runREPL.
+// history is saved to historyFile if it is not nil
+type repl struct {
+	out, errOut io.Writer
+	history     []string
+	historyFile io.Writer
+}
+
+// runREPL run `teltechcc repl`, commands are read from stdin until EOF or :quit.
+// commands are the same as TCP protocol, or expression of /eval.
+// validation, calculation and cache are the same as the HTTP server,
+// cache is local memory unless --redis is set, and is configured by the same flags as the server
+func runREPL(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("teltechcc repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cf cacheFlags
+	cf.register(fs, "redis url, same as the server. If not set, will use local memory which is lost on exit")
+	historyPath := fs.String("history", defaultHistoryPath(), "file history is loaded from and saved to. empty to disable")
+	err := fs.Parse(args)
+	if err != nil {
+		return exitUsage
+	}
+
+	cache, err = cf.newCache()
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitUsage
+	}
+	defer cache.Close()
+	if err := cache.Ping(); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitUnavailable
+	}
+
+	r := &repl{out: stdout, errOut: stderr}
+	if *historyPath != "" {
+		f, err := r.loadHistory(*historyPath)
+		if err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+			return exitUsage
+		}
+		defer f.Close()
+		r.historyFile = f
+	}
+	r.run(stdin)
+	return exitOK
+}
+
+// defaultHistoryPath is ~/.teltechcc_history, empty if home is unknown
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".teltechcc_history")
+}
+
+// loadHistory read history from path, file is returned to append new commands
+func (r *repl) loadHistory(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			r.history = append(r.history, line)
+		}
+	}
+	return f, scanner.Err()
+}
+
+// run read commands from in until EOF or :quit
+func (r *repl) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(r.out, replPrompt)
+	for scanner.Scan() {
+		if r.exec(strings.TrimSpace(scanner.Text())) == false {
+			return
+		}
+		fmt.Fprint(r.out, replPrompt)
+	}
+	fmt.Fprintln(r.out)
+}
+
+// exec run line, false is returned if repl should exit
+func (r *repl) exec(line string) bool {
+	if line == "" {
+		return true
+	}
+	if strings.HasPrefix(line, "!") {
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 1 || n > len(r.history) {
+			r.printError(&fieldError{field: "history", value: line[1:], err: errHistory}, codeInvalidParameter)
+			return true
+		}
+		line = r.history[n-1]
+		fmt.Fprintln(r.out, line)
+	}
+	r.addHistory(line)
+
+	switch line {
+	case ":quit", ":q":
+		return false
+	case ":help":
+		fmt.Fprintln(r.out, replHelp)
+	case ":history":
+		for i, h := range r.history {
+			fmt.Fprintf(r.out, "%5d  %v\n", i+1, h)
+		}
+	case ":stats":
+		status := healthStatus()
+		if status["cache"] != "OK" {
+			fmt.Fprintln(r.errOut, "error:", status["cache"])
+			break
+		}
+		fmt.Fprintf(r.out, "hit %v, size %v\n", status["hit"], status["size"])
+	case ":flush":
+		cache.Flush()
+		fmt.Fprintln(r.out, "flushed")
+	default:
+		if strings.HasPrefix(line, ":") {
+			fmt.Fprintf(r.errOut, "error: unknown command %v, see :help\n", line)
+			break
+		}
+		r.calc(line)
+	}
+	return true
+}
+
+// calc run command of an operation, like add 2 5, or evaluate line as expression.
+// errors have the same detail and code as the HTTP API
+func (r *repl) calc(line string) {
+	fields := strings.Fields(line)
+	if _, ok := findOp(strings.ToLower(fields[0])); ok {
+		result, cached, err := execCommand(fields)
+		if err != nil {
+			r.printError(err, codeCalculation)
+			return
+		}
+		if cached {
+			fmt.Fprintf(r.out, "%v (cached)\n", result)
+			return
+		}
+		fmt.Fprintln(r.out, result)
+		return
+	}
+
+	if len(line) > maxExprLength {
+		r.printError(errExprLength, codeInvalidExpr)
+		return
+	}
+	tree, err := expr.Parse(line)
+	if err != nil {
+		r.printError(err, codeInvalidExpr)
+		return
+	}
+	stats := &evalStats{}
+	result, err := evaluate(tree, opTTL, stats)
+	if err != nil {
+		r.printError(err, codeInvalidExpr)
+		return
+	}
+	if stats.hit > 0 {
+		fmt.Fprintf(r.out, "%v (%d of %d cached)\n", result, stats.hit, stats.nodes)
+		return
+	}
+	fmt.Fprintln(r.out, result)
+}
+
+func (r *repl) addHistory(line string) {
+	r.history = append(r.history, line)
+	if r.historyFile != nil {
+		fmt.Fprintln(r.historyFile, line)
+	}
+}
+
+// printError print err as `error: <detail> (<code>)`, fallback is the code if err is unknown
+func (r *repl) printError(err error, fallback string) {
+	fmt.Fprintf(r.errOut, "error: %v (%v)\n", err, errorCode(err, fallback))
+}
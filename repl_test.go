@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	setUpLogger(false)
+	history := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(history, []byte("add 1 1\n"), 0600)
+
+	stdin := strings.Join([]string{
+		"add 2 5",
+		"ADD 5 2",
+		"(2+5)*3",
+		"sub 1",
+		"div 1 0",
+		"2 +",
+		":stats",
+		"!2",
+		"!99",
+		":flush",
+		":stats",
+		":nope",
+		":history",
+		":quit",
+		"add 3 3",
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	code := runREPL([]string{"--history", history}, strings.NewReader(stdin), &stdout, &stderr)
+
+	expOut := strings.Join([]string{
+		"> 7",
+		"> 7 (cached)",
+		"> 21 (1 of 2 cached)",
+		"> > > > hit 2, size 2",
+		"> add 2 5",
+		"7 (cached)",
+		"> > flushed",
+		"> hit 3, size 0",
+		"> >     1  add 1 1",
+		"    2  add 2 5",
+		"    3  ADD 5 2",
+		"    4  (2+5)*3",
+		"    5  sub 1",
+		"    6  div 1 0",
+		"    7  2 +",
+		"    8  :stats",
+		"    9  add 2 5",
+		"   10  :flush",
+		"   11  :stats",
+		"   12  :nope",
+		"   13  :history",
+		"> ",
+	}, "\n")
+	expErr := strings.Join([]string{
+		"error: " + errOperandCount(getOp("sub")).Error() + " (operand_count)",
+		"error: " + errDivideByZero.Error() + " (divide_by_zero)",
+		"error: unexpected end of expression at position 3 (invalid_expression)",
+		"error: " + errHistory.Error() + " (invalid_parameter)",
+		"error: unknown command :nope, see :help",
+		"",
+	}, "\n")
+	if code != exitOK || stdout.String() != expOut || stderr.String() != expErr {
+		t.Errorf("error on: case repl\ngot code, stdout and stderr:\n %v \n%v\n%v \nexp code, stdout and stderr\n %v \n%v\n%v \n",
+			code, stdout.String(), stderr.String(), exitOK, expOut, expErr)
+	}
+
+	saved, _ := os.ReadFile(history)
+	if strings.HasSuffix(string(saved), "add 2 5\n:flush\n:stats\n:nope\n:history\n:quit\n") == false {
+		t.Errorf("error on: case history file\ngot history:\n %v \n", string(saved))
+	}
+}
+
+// TestREPLCacheFlags checks cache of repl is configured by the same flags as the server
+func TestREPLCacheFlags(t *testing.T) {
+	setUpLogger(false)
+	ttl, ttls := defaultTTL, opTTLs
+	defer func() { defaultTTL, opTTLs = ttl, ttls }()
+
+	cases := []struct {
+		name    string
+		args    []string
+		stdin   string
+		expCode int
+		expOut  string
+		expErr  string
+	}{
+		{
+			name:    "case max entries",
+			args:    []string{"--history", "", "--cache-max-entries", "1"},
+			stdin:   "add 2 5\nadd 2 6\n:stats",
+			expCode: exitOK,
+			expOut:  "> 7\n> 8\n> hit 0, size 1\n> \n",
+		},
+		{
+			name:    "case invalid ttl",
+			args:    []string{"--history", "", "--ttl", "-1s"},
+			expCode: exitUsage,
+			expErr:  "error: " + errTTL.Error() + "\n",
+		},
+		{
+			name:    "case invalid cache limit",
+			args:    []string{"--history", "", "--cache-max-bytes", "-1"},
+			expCode: exitUsage,
+			expErr:  "error: " + errCacheLimit.Error() + "\n",
+		},
+	}
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		code := runREPL(c.args, strings.NewReader(c.stdin), &stdout, &stderr)
+		if code != c.expCode || stdout.String() != c.expOut || stderr.String() != c.expErr {
+			t.Errorf("error on: %v\ngot code, stdout and stderr:\n %v \n%v\n%v \nexp code, stdout and stderr\n %v \n%v\n%v \n",
+				c.name, code, stdout.String(), stderr.String(), c.expCode, c.expOut, c.expErr)
+		}
+	}
+}
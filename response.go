@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/protobuf/proto"
+	"net/http"
+	"sort"
+)
+
+// Response is the protobuf message of response, see response.proto.
+// handlers build response as gin.H, keys of gin.H are the field names here,
+// so that every format shares the same schema
+type Response struct {
+	Action      string   `protobuf:"bytes,1,opt,name=action,proto3"`
+	X           string   `protobuf:"bytes,2,opt,name=x,proto3"`
+	Y           string   `protobuf:"bytes,3,opt,name=y,proto3"`
+	Values      []string `protobuf:"bytes,4,rep,name=values"`
+	Answer      string   `protobuf:"bytes,5,opt,name=answer,proto3"`
+	Cached      bool     `protobuf:"varint,6,opt,name=cached,proto3"`
+	Remainder   string   `protobuf:"bytes,7,opt,name=remainder,proto3"`
+	Mode        string   `protobuf:"bytes,8,opt,name=mode,proto3"`
+	Type        string   `protobuf:"bytes,9,opt,name=type,proto3"`
+	Scale       int64    `protobuf:"varint,10,opt,name=scale,proto3"`
+	Rounding    string   `protobuf:"bytes,11,opt,name=rounding,proto3"`
+	Precision   string   `protobuf:"bytes,12,opt,name=precision,proto3"`
+	Numerator   string   `protobuf:"bytes,13,opt,name=numerator,proto3"`
+	Denominator string   `protobuf:"bytes,14,opt,name=denominator,proto3"`
+	Decimal     string   `protobuf:"bytes,15,opt,name=decimal,proto3"`
+	Code        string   `protobuf:"bytes,17,opt,name=code,proto3"`
+	Pos         int64    `protobuf:"varint,18,opt,name=pos,proto3"`
+	Expr        string   `protobuf:"bytes,19,opt,name=expr,proto3"`
+	Nodes       int64    `protobuf:"varint,20,opt,name=nodes,proto3"`
+	Hit         int64    `protobuf:"varint,21,opt,name=hit,proto3"`
+	Cache       string   `protobuf:"bytes,22,opt,name=cache,proto3"`
+	Size        int64    `protobuf:"varint,23,opt,name=size,proto3"`
+	Title       string   `protobuf:"bytes,24,opt,name=title,proto3"`
+	Status      int64    `protobuf:"varint,25,opt,name=status,proto3"`
+	Detail      string   `protobuf:"bytes,26,opt,name=detail,proto3"`
+	Instance    string   `protobuf:"bytes,27,opt,name=instance,proto3"`
+	Field       string   `protobuf:"bytes,28,opt,name=field,proto3"`
+	Value       string   `protobuf:"bytes,29,opt,name=value,proto3"`
+	RequestID   string   `protobuf:"bytes,30,opt,name=request_id,json=requestId,proto3"`
+	Op          string   `protobuf:"bytes,31,opt,name=op,proto3"`
+	Evicted     int64    `protobuf:"varint,32,opt,name=evicted,proto3"`
+	Expired     int64    `protobuf:"varint,33,opt,name=expired,proto3"`
+}
+
+func (m *Response) Reset()         { *m = Response{} }
+func (m *Response) String() string { return proto.CompactTextString(m) }
+func (*Response) ProtoMessage()    {}
+
+// BatchResponse is the protobuf message of batch response
+type BatchResponse struct {
+	Results []*Response `protobuf:"bytes,1,rep,name=results"`
+}
+
+func (m *BatchResponse) Reset()         { *m = BatchResponse{} }
+func (m *BatchResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchResponse) ProtoMessage()    {}
+
+// newResponse convert h to Response.
+// numbers of x, y, values, answer and remainder are formatted as string.
+// it panics if key is not in schema, every key should be added to response.proto first
+func newResponse(h gin.H) *Response {
+	r := &Response{}
+	for k, v := range h {
+		switch k {
+		case "action":
+			r.Action = fmt.Sprint(v)
+		case "x":
+			r.X = fmt.Sprint(v)
+		case "y":
+			r.Y = fmt.Sprint(v)
+		case "values":
+			for _, value := range v.([]interface{}) {
+				r.Values = append(r.Values, fmt.Sprint(value))
+			}
+		case "answer":
+			r.Answer = fmt.Sprint(v)
+		case "cached":
+			r.Cached = v.(bool)
+		case "remainder":
+			r.Remainder = fmt.Sprint(v)
+		case "mode":
+			r.Mode = fmt.Sprint(v)
+		case "type":
+			r.Type = fmt.Sprint(v)
+		case "scale":
+			r.Scale = int64(v.(int))
+		case "rounding":
+			r.Rounding = fmt.Sprint(v)
+		case "precision":
+			r.Precision = fmt.Sprint(v)
+		case "numerator":
+			r.Numerator = fmt.Sprint(v)
+		case "denominator":
+			r.Denominator = fmt.Sprint(v)
+		case "decimal":
+			r.Decimal = fmt.Sprint(v)
+		case "code":
+			r.Code = fmt.Sprint(v)
+		case "pos":
+			r.Pos = int64(v.(int))
+		case "expr":
+			r.Expr = fmt.Sprint(v)
+		case "nodes":
+			r.Nodes = int64(v.(int))
+		case "hit":
+			r.Hit = int64(v.(int))
+		case "cache":
+			r.Cache = fmt.Sprint(v)
+		case "size":
+			r.Size = int64(v.(int))
+		case "title":
+			r.Title = fmt.Sprint(v)
+		case "status":
+			r.Status = int64(v.(int))
+		case "detail":
+			r.Detail = fmt.Sprint(v)
+		case "instance":
+			r.Instance = fmt.Sprint(v)
+		case "field":
+			r.Field = fmt.Sprint(v)
+		case "value":
+			r.Value = fmt.Sprint(v)
+		case "request_id":
+			r.RequestID = fmt.Sprint(v)
+		case "op":
+			r.Op = fmt.Sprint(v)
+		case "evicted":
+			r.Evicted = int64(v.(int))
+		case "expired":
+			r.Expired = int64(v.(int))
+		default:
+			panic(fmt.Sprintf("%v is not in response schema", k))
+		}
+	}
+	return r
+}
+
+// protoData convert response of handlers to protobuf message
+func protoData(data interface{}) proto.Message {
+	switch data := data.(type) {
+	case gin.H:
+		return newResponse(data)
+	case []gin.H:
+		batch := &BatchResponse{Results: make([]*Response, len(data))}
+		for i, h := range data {
+			batch.Results[i] = newResponse(h)
+		}
+		return batch
+	}
+	panic(fmt.Sprintf("unsupported response %T", data))
+}
+
+// protoBuf render proto.Message, gin/render doesn't have one
+type protoBuf struct {
+	Data proto.Message
+}
+
+var protoBufContentType = []string{mimeProtoBuf}
+
+func (r protoBuf) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	b, err := proto.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r protoBuf) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = protoBufContentType
+	}
+}
+
+// xmlResponse render gin.H as `<response>` with sorted elements,
+// slice is rendered as repeated elements, like `<values>1</values><values>2</values>`.
+// gin.H itself can be rendered as xml, but root is `<map>` and order is random
+type xmlResponse gin.H
+
+func (h xmlResponse) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalSorted(e, xml.StartElement{Name: xml.Name{Local: "response"}}, gin.H(h))
+}
+
+// marshalSorted encode h as children of start in order of keys
+func marshalSorted(e *xml.Encoder, start xml.StartElement, h gin.H) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.EncodeElement(h[k], xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xmlBatchResponse render batch response as `<responses>` of `<response>`
+type xmlBatchResponse struct {
+	XMLName   xml.Name      `xml:"responses"`
+	Responses []xmlResponse `xml:"response"`
+}
+
+// xmlData convert response of handlers to xml marshaler
+func xmlData(data interface{}) interface{} {
+	switch data := data.(type) {
+	case gin.H:
+		return xmlResponse(data)
+	case []gin.H:
+		batch := xmlBatchResponse{Responses: make([]xmlResponse, len(data))}
+		for i, h := range data {
+			batch.Responses[i] = xmlResponse(h)
+		}
+		return batch
+	}
+	panic(fmt.Sprintf("unsupported response %T", data))
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+const jsonRPCVersion = "2.0"
+
+// JSON-RPC 2.0 error codes.
+// -32000 to -32099 are reserved for implementation-defined server errors
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcCalcError      = -32000
+)
+
+// rpcMessages are messages of JSON-RPC error codes
+var rpcMessages = map[int]string{
+	rpcParseError:     "Parse error",
+	rpcInvalidRequest: "Invalid Request",
+	rpcMethodNotFound: "Method not found",
+	rpcInvalidParams:  "Invalid params",
+	rpcInternalError:  "Internal error",
+	rpcCalcError:      "Calculation error",
+}
+
+var errRPCParse = fmt.Errorf("Invalid JSON")
+var errRPCRequest = fmt.Errorf("Invalid request. JSON object of jsonrpc 2.0, method, params and id only")
+var errRPCParams = fmt.Errorf("Invalid params. JSON object of x and y (or values), or JSON array of operands only")
+
+// errRPCMethod is returned if method is not supported
+func errRPCMethod(method string) error {
+	return fmt.Errorf("%w %q", errUnsupportedOp, method)
+}
+
+// rpcRequest is a JSON-RPC request.
+// id is nil if it is missing, the request is a notification then
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcParams are named params of calculation methods,
+// same as POST body of endpoints. mode is used by divide only
+type rpcParams struct {
+	X      json.RawMessage   `json:"x"`
+	Y      json.RawMessage   `json:"y"`
+	Values []json.RawMessage `json:"values"`
+	Mode   string            `json:"mode"`
+}
+
+// rpcError is the error of JSON-RPC response, data is problem details
+type rpcError struct {
+	code int
+	err  error
+}
+
+// rpc endpoint, JSON-RPC 2.0 over HTTP.
+// methods are actions of registered operations, like add and subtract, and health.
+// batch array is supported, notifications get no response,
+// 204 is returned if there is nothing to respond.
+// Cache-Control is honored by every request, same as calculation endpoints
+func rpc(ctx *gin.Context) {
+	ttl, err := requestTTL(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	body, err := ctx.GetRawData()
+	if err != nil || json.Valid(body) == false {
+		ctx.JSON(200, rpcResponse(nil, nil, &rpcError{code: rpcParseError, err: errRPCParse}, ctx.GetString(requestIDKey)))
+		return
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		resp := handleRPC(json.RawMessage(body), ttl, ctx.GetString(requestIDKey))
+		if resp == nil {
+			ctx.Status(204)
+			return
+		}
+		ctx.JSON(200, resp)
+		return
+	}
+
+	var requests []json.RawMessage
+	json.Unmarshal(body, &requests)
+	if len(requests) == 0 {
+		ctx.JSON(200, rpcResponse(nil, nil, &rpcError{code: rpcInvalidRequest, err: errRPCRequest}, ctx.GetString(requestIDKey)))
+		return
+	}
+	if len(requests) > maxBatchSize {
+		ctx.JSON(200, rpcResponse(nil, nil, &rpcError{code: rpcInvalidRequest, err: errBatchSize}, ctx.GetString(requestIDKey)))
+		return
+	}
+	responses := make([]gin.H, 0, len(requests))
+	for _, raw := range requests {
+		if resp := handleRPC(raw, ttl, ctx.GetString(requestIDKey)); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		ctx.Status(204)
+		return
+	}
+	ctx.JSON(200, responses)
+}
+
+// handleRPC handle a single request, nil is returned for notification.
+// ttl return TTL of each op, see requestTTL
+func handleRPC(raw json.RawMessage, ttl func(name string) time.Duration, requestID string) gin.H {
+	var req rpcRequest
+	err := json.Unmarshal(raw, &req)
+	if err != nil || req.JSONRPC != jsonRPCVersion || req.Method == "" || validRPCID(req.ID) == false {
+		return rpcResponse(nil, nil, &rpcError{code: rpcInvalidRequest, err: errRPCRequest}, requestID)
+	}
+	Debug.Println("recieved rpc:", req.Method, string(req.Params))
+	result, rpcErr := callRPC(req.Method, req.Params, ttl)
+	if req.ID == nil {
+		return nil
+	}
+	return rpcResponse(req.ID, result, rpcErr, requestID)
+}
+
+// validRPCID checks id is string, number, null or missing
+func validRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v interface{}
+	json.Unmarshal(id, &v)
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+// rpcResponse return JSON-RPC response of result or rpcErr, id is null if it is unknown
+func rpcResponse(id json.RawMessage, result interface{}, rpcErr *rpcError, requestID string) gin.H {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if rpcErr == nil {
+		return gin.H{"jsonrpc": jsonRPCVersion, "result": result, "id": id}
+	}
+	fallback := codeInternal
+	if rpcErr.code == rpcCalcError {
+		fallback = codeCalculation
+	}
+	return gin.H{"jsonrpc": jsonRPCVersion, "id": id, "error": gin.H{
+		"code":    rpcErr.code,
+		"message": rpcMessages[rpcErr.code],
+		"data":    problemOf(rpcErr.err, fallback).body("", requestID),
+	}}
+}
+
+// callRPC call method with params, result is the same as response of endpoints.
+// ttl return TTL of each op, see requestTTL
+func callRPC(method string, params json.RawMessage, ttl func(name string) time.Duration) (gin.H, *rpcError) {
+	if method == "health" {
+		return healthStatus(), nil
+	}
+	op, ok := rpcOp(method)
+	if ok == false {
+		return nil, &rpcError{code: rpcMethodNotFound, err: errRPCMethod(method)}
+	}
+	values, list, mode, err := rpcOperands(op, params)
+	if err != nil {
+		return nil, &rpcError{code: rpcInvalidParams, err: err}
+	}
+	intValues, err := opValidation(op, values)
+	if err != nil {
+		return nil, &rpcError{code: rpcInvalidParams, err: namedOperand(err, list)}
+	}
+	resp := operandFields(list, len(intValues), func(i int) interface{} { return intValues[i] })
+	resp["action"] = op.Action
+	if op.Name == "div" {
+		result, remainder, cached, err := getDivResult(ttl(op.Name), mode, intValues...)
+		if err != nil {
+			return nil, &rpcError{code: rpcCalcError, err: err}
+		}
+		resp["answer"], resp["mode"], resp["cached"] = result, mode, cached
+		if list == false {
+			resp["remainder"] = remainder
+		}
+		return resp, nil
+	}
+	result, cached, err := getResult(ttl(op.Name), op.Name, intValues...)
+	if err != nil {
+		return nil, &rpcError{code: rpcCalcError, err: err}
+	}
+	resp["answer"], resp["cached"] = result, cached
+	return resp, nil
+}
+
+// rpcOp return registered operation by action, which is the method name
+func rpcOp(method string) (ops.Operation, bool) {
+	for _, op := range ops.All() {
+		if op.Action == method {
+			return op, true
+		}
+	}
+	return ops.Operation{}, false
+}
+
+// rpcOperands return values of op from params, same as getOperands.
+// params by name is JSON object of x and y (or values), and mode for divide.
+// params by position is JSON array of operands
+func rpcOperands(op ops.Operation, params json.RawMessage) ([]string, bool, string, error) {
+	mode := defaultDivMode
+	if bytes.HasPrefix(bytes.TrimSpace(params), []byte("[")) {
+		var raws []json.RawMessage
+		json.Unmarshal(params, &raws)
+		values := make([]string, len(raws))
+		for i, raw := range raws {
+			values[i] = rawOperand(raw)
+		}
+		if op.Arity == 2 && len(values) == op.Arity {
+			return values, false, mode, qsValidation(values[0], values[1])
+		}
+		return values, true, mode, operandsValidation(op, values)
+	}
+
+	var p rpcParams
+	err := json.Unmarshal(params, &p)
+	if err != nil {
+		return nil, false, "", errRPCParams
+	}
+	if p.Mode != "" {
+		mode, err = divModeValidation(p.Mode)
+		if err != nil {
+			return nil, false, "", err
+		}
+	}
+	if len(p.Values) == 0 && op.Arity == 2 {
+		x, y := rawOperand(p.X), rawOperand(p.Y)
+		return []string{x, y}, false, mode, qsValidation(x, y)
+	}
+	values := make([]string, len(p.Values))
+	for i, raw := range p.Values {
+		values[i] = rawOperand(raw)
+	}
+	return values, true, mode, operandsValidation(op, values)
+}
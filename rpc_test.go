@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"testing"
+)
+
+// rpcErrorBody return expected JSON-RPC error response, data is problem details of err
+func rpcErrorBody(id string, code int, problemCode string, err error, field, value string) gin.H {
+	return gin.H{"jsonrpc": "2.0", "id": json.RawMessage(id), "error": gin.H{
+		"code": code, "message": rpcMessages[code], "data": problemBody("", problemCode, err, field, value),
+	}}
+}
+
+func rpcResultBody(id string, result gin.H) gin.H {
+	return gin.H{"jsonrpc": "2.0", "id": json.RawMessage(id), "result": result}
+}
+
+func TestRPC(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, body    string
+		expStatusCode int
+		expBody       interface{}
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case named", body: `{"jsonrpc": "2.0", "method": "add", "params": {"x": 1, "y": "3"}, "id": 1}`, expStatusCode: 200,
+			expBody: rpcResultBody("1", gin.H{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": false}),
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case cached", body: `{"jsonrpc": "2.0", "method": "add", "params": {"x": 3, "y": 1}, "id": "a"}`, expStatusCode: 200,
+			expBody: rpcResultBody(`"a"`, gin.H{"action": "add", "x": 3, "y": 1, "answer": 4, "cached": true}),
+			fCache:  &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
+		},
+		{
+			name: "case positional", body: `{"jsonrpc": "2.0", "method": "subtract", "params": [10, 3, 2], "id": 2}`, expStatusCode: 200,
+			expBody: rpcResultBody("2", gin.H{"action": "subtract", "values": []int64{10, 3, 2}, "answer": 5, "cached": false}),
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case divide mode", body: `{"jsonrpc": "2.0", "method": "divide", "params": {"x": -7, "y": 2, "mode": "trunc"}, "id": 3}`, expStatusCode: 200,
+			expBody: rpcResultBody("3", gin.H{"action": "divide", "x": -7, "y": 2, "answer": -3, "remainder": -1, "mode": "trunc", "cached": false}),
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case health", body: `{"jsonrpc": "2.0", "method": "health", "id": null}`, expStatusCode: 200,
+			expBody: rpcResultBody("null", gin.H{"cache": "OK", "hit": 0, "size": 0}),
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case notification", body: `{"jsonrpc": "2.0", "method": "add", "params": [1, 2]}`, expStatusCode: 204,
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case parse error", body: `{"jsonrpc": "2.0", "method"`, expStatusCode: 200,
+			expBody: rpcErrorBody("null", rpcParseError, codeInvalidBody, errRPCParse, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case invalid request", body: `{"jsonrpc": "1.0", "method": "add", "id": 1}`, expStatusCode: 200,
+			expBody: rpcErrorBody("null", rpcInvalidRequest, codeInvalidBody, errRPCRequest, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case invalid id", body: `{"jsonrpc": "2.0", "method": "add", "id": {}}`, expStatusCode: 200,
+			expBody: rpcErrorBody("null", rpcInvalidRequest, codeInvalidBody, errRPCRequest, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case method not found", body: `{"jsonrpc": "2.0", "method": "pow", "params": [1, 2], "id": 4}`, expStatusCode: 200,
+			expBody: rpcErrorBody("4", rpcMethodNotFound, codeUnsupported, errRPCMethod("pow"), "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case miss y", body: `{"jsonrpc": "2.0", "method": "add", "params": {"x": 1}, "id": 5}`, expStatusCode: 200,
+			expBody: rpcErrorBody("5", rpcInvalidParams, codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case type", body: `{"jsonrpc": "2.0", "method": "add", "params": [1, 2.5, 3], "id": 6}`, expStatusCode: 200,
+			expBody: rpcErrorBody("6", rpcInvalidParams, codeInvalidType, errType, "values[1]", "2.5"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case divide by zero", body: `{"jsonrpc": "2.0", "method": "divide", "params": [1, 0], "id": 7}`, expStatusCode: 200,
+			expBody: rpcErrorBody("7", rpcInvalidParams, codeDivideByZero, errDivideByZero, "y", "0"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case invalid params", body: `{"jsonrpc": "2.0", "method": "add", "params": "1,2", "id": 8}`, expStatusCode: 200,
+			expBody: rpcErrorBody("8", rpcInvalidParams, codeInvalidBody, errRPCParams, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case overflow", body: `{"jsonrpc": "2.0", "method": "multiply", "params": [9223372036854775807, 2], "id": 9}`, expStatusCode: 200,
+			expBody: rpcErrorBody("9", rpcCalcError, codeOverflow, errOverflow, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case empty batch", body: `[]`, expStatusCode: 200,
+			expBody: rpcErrorBody("null", rpcInvalidRequest, codeInvalidBody, errRPCRequest, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case batch", expStatusCode: 200,
+			body: `[{"jsonrpc": "2.0", "method": "multiply", "params": [2, 3], "id": 1}, {"jsonrpc": "2.0", "method": "add", "params": [1, 2]},
+				1, {"jsonrpc": "2.0", "method": "divide", "params": {"x": 1}, "id": 2}]`,
+			expBody: []gin.H{
+				rpcResultBody("1", gin.H{"action": "multiply", "x": 2, "y": 3, "answer": 6, "cached": false}),
+				rpcErrorBody("null", rpcInvalidRequest, codeInvalidBody, errRPCRequest, "", ""),
+				rpcErrorBody("2", rpcInvalidParams, codeMissingOperand, errMissY, "y", ""),
+			},
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case batch of notifications", body: `[{"jsonrpc": "2.0", "method": "add", "params": [1, 2]}]`, expStatusCode: 204,
+			fCache: NewFakeCache(),
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequestWithBody(router, "POST", "/rpc", "application/json", c.body)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		exp := ""
+		if c.expBody != nil {
+			jsonEncoded, _ := json.Marshal(c.expBody)
+			exp = string(jsonEncoded)
+		}
+		if w.Body.String() != exp {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), exp)
+		}
+	}
+}
+
+func TestRPCSharedCache(t *testing.T) {
+	setUpLogger(false)
+	cache = NewFakeCache()
+	router := newRouter()
+	performRequest(router, "GET", "/add?x=1&y=2")
+	w := performRequestWithBody(router, "POST", "/rpc", "application/json", `{"jsonrpc": "2.0", "method": "add", "params": [2, 1], "id": 1}`)
+	jsonEncoded, _ := json.Marshal(rpcResultBody("1", gin.H{"action": "add", "x": 2, "y": 1, "answer": 3, "cached": true}))
+	if w.Body.String() != string(jsonEncoded) {
+		t.Errorf("error on: shared cache\ngot body:\n %v \nexp body\n %v \n", w.Body.String(), string(jsonEncoded))
+	}
+	if cache.GetCounter() != 1 {
+		t.Errorf("error on: shared cache\ngot hit:\n %v \nexp hit\n %v \n", cache.GetCounter(), 1)
+	}
+}
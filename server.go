@@ -2,90 +2,381 @@ package main
 
 import (
 	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 func newServer(ip string, port int) *http.Server {
 	addr := fmt.Sprintf("%v:%v", ip, port)
 	r := newRouter()
-	return &http.Server{Addr: addr, Handler: r}
+	s := &http.Server{Addr: addr, Handler: r}
+	// hijacked WebSocket connections are not closed by Shutdown,
+	// and event streams never become idle
+	s.RegisterOnShutdown(websockets.closeAll)
+	s.RegisterOnShutdown(broker.closeAll)
+	return s
 }
 
 func newRouter() *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+	// errors, including 404, 405 and panic, are responded as problem details, see respondProblem
+	r.Use(gin.Logger(), requestID, recovery)
 	// if route match but not get method. return 405
 	r.HandleMethodNotAllowed = true
-	r.GET("/add", add)
-	r.GET("/subtract", subtract)
-	r.GET("/multiply", multiply)
-	r.GET("/divide", divide)
-	r.GET("/health", health)
+	r.NoRoute(notFound)
+	r.NoMethod(methodNotAllowed)
+	// OpenAPI document, docs page, JSON-RPC, WebSocket and event stream are not negotiated
+	r.GET("/openapi.json", openAPIHandler(r))
+	r.POST("/rpc", rpc)
+	r.GET("/ws", ws)
+	r.GET("/events", events)
+	if serveDocs {
+		r.GET("/docs", docs)
+	}
+
+	// every version shares the same handlers, response is converted by versioned.
+	// unversioned routes are aliases of v1.
+	// response format is negotiated by Accept header, see respond. negotiate is not used by r,
+	// otherwise 404 and 405 would be answered by 406 too
+	addRoutes(r.Group("/", negotiate, apiVersion(v1)))
+	addRoutes(r.Group("/"+v1, negotiate, apiVersion(v1)))
+	addRoutes(r.Group("/"+v2, negotiate, apiVersion(v2)))
 	return r
 }
 
-//add, subtract, multiply, and divide
-func add(ctx *gin.Context) {
-	x, y := ctx.Query("x"), ctx.Query("y")
-	intX, intY, err := addValidation(x, y)
-	if err != nil {
-		ctx.JSON(400, gin.H{"err": err.Error()})
-		return
+// addRoutes register all endpoints in g.
+// routes are built from registered operations, see package ops
+func addRoutes(g *gin.RouterGroup) {
+	rational := g.Group("/" + typeRational)
+	for _, op := range ops.All() {
+		handler, ok := customHandlers[op.Name]
+		if ok == false {
+			handler = calcHandler(op)
+		}
+		g.GET(op.Route, handler)
+		// operands are read from request body, see bodyOperands
+		g.POST(op.Route, handler)
+		if op.Rat != nil {
+			rational.GET(op.Route, rationalHandler(op))
+		}
+	}
+	g.GET("/eval", eval)
+	g.POST("/batch", batch)
+	g.GET("/health", health)
+}
+
+// customHandlers are used instead of calcHandler
+// for operations accept extra query string
+var customHandlers = map[string]gin.HandlerFunc{
+	"div": divide,
+}
+
+// calcHandler return handler of op, like add, subtract, multiply.
+// values are int64 by default, see handleDecimal and handleBig for other types
+func calcHandler(op ops.Operation) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if handleDecimal(ctx, op) || handleBig(ctx, op) {
+			return
+		}
+		values, list, err := getOperands(ctx, op)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		intValues, err := opValidation(op, values)
+		if err != nil {
+			respondError(ctx, namedOperand(err, list))
+			return
+		}
+		ttl, err := requestTTL(ctx)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		Debug.Println("recieved:", intValues)
+		result, cached, err := getResult(ttl(op.Name), op.Name, intValues...)
+		if err != nil {
+			calcError(ctx, err)
+			return
+		}
+		resp := operandFields(list, len(intValues), func(i int) interface{} { return intValues[i] })
+		resp["action"], resp["answer"], resp["cached"] = op.Action, result, cached
+		respond(ctx, 200, resp)
 	}
-	Debug.Println("recieved:", intX, intY)
-	result, cached := getResult("add", intX, intY)
-	ctx.JSON(200, gin.H{"action": "add", "x": intX, "y": intY, "answer": result, "cached": cached})
 }
 
-func subtract(ctx *gin.Context) {
-	x, y := ctx.Query("x"), ctx.Query("y")
-	intX, intY, err := subValidation(x, y)
+// divide is floor function by default.
+// example: 1 / 3 =0, 4 / 3 = 1, -1 / 2 = -1
+// rounding mode can be changed via mode in query string, see ops.DivModes.
+// remainder matching the quotient will be returned as well if there are only x and y.
+// mode is ignored for decimal, which use rounding instead
+func divide(ctx *gin.Context) {
+	op := getOp("div")
+	mode, err := divModeValidation(ctx.DefaultQuery("mode", defaultDivMode))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	if handleDecimal(ctx, op) || handleBig(ctx, op) {
+		return
+	}
+	values, list, err := getOperands(ctx, op)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	intValues, err := opValidation(op, values)
 	if err != nil {
-		ctx.JSON(400, gin.H{"err": err.Error()})
+		respondError(ctx, namedOperand(err, list))
 		return
 	}
-	Debug.Println("recieved:", intX, intY)
-	result, cached := getResult("sub", intX, intY)
+	ttl, err := requestTTL(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	Debug.Println("recieved:", intValues)
+	result, remainder, cached, err := getDivResult(ttl(op.Name), mode, intValues...)
+	if err != nil {
+		calcError(ctx, err)
+		return
+	}
+	resp := operandFields(list, len(intValues), func(i int) interface{} { return intValues[i] })
+	resp["action"], resp["answer"], resp["mode"], resp["cached"] = op.Action, result, mode, cached
+	if list == false {
+		resp["remainder"] = remainder
+	}
+	respond(ctx, 200, resp)
+}
 
-	ctx.JSON(200, gin.H{"action": "subtract", "x": intX, "y": intY, "answer": result, "cached": cached})
+// getOperands return values of op from query string, or request body if method is POST.
+// values can be x and y, repeated v (v=1&v=2&v=3) or comma separated values (values=1,2,3).
+// x and y are ignored if v or values is set, or if op is not binary.
+// list is true if values are not from x and y, response should return them as values as well
+func getOperands(ctx *gin.Context, op ops.Operation) ([]string, bool, error) {
+	var x, y string
+	var values []string
+	if ctx.Request.Method == "POST" {
+		var err error
+		x, y, values, err = bodyOperands(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		x, y, values = queryOperands(ctx)
+	}
+	if len(values) == 0 && op.Arity == 2 {
+		return []string{x, y}, false, qsValidation(x, y)
+	}
+	return values, true, operandsValidation(op, values)
 }
 
-func multiply(ctx *gin.Context) {
-	x, y := ctx.Query("x"), ctx.Query("y")
-	intX, intY, err := mulValidation(x, y)
-	if err != nil {
-		ctx.JSON(400, gin.H{"err": err.Error()})
-		return
+// queryOperands return x, y and values from query string
+func queryOperands(ctx *gin.Context) (string, string, []string) {
+	values := ctx.QueryArray("v")
+	if s, ok := ctx.GetQuery("values"); ok {
+		values = append(values, strings.Split(s, ",")...)
 	}
-	Debug.Println("recieved:", intX, intY)
-	result, cached := getResult("mul", intX, intY)
+	return ctx.Query("x"), ctx.Query("y"), values
+}
 
-	ctx.JSON(200, gin.H{"action": "multiply", "x": intX, "y": intY, "answer": result, "cached": cached})
+// operandFields return operands of response.
+// x and y if list is false, otherwise values in order of request
+func operandFields(list bool, n int, value func(i int) interface{}) gin.H {
+	if list == false {
+		return gin.H{"x": value(0), "y": value(1)}
+	}
+	values := make([]interface{}, n)
+	for i := range values {
+		values[i] = value(i)
+	}
+	return gin.H{"values": values}
 }
 
-// divide is floor function.
-// example: 1 / 3 =0, 4 / 3 = 1
-func divide(ctx *gin.Context) {
-	x, y := ctx.Query("x"), ctx.Query("y")
-	intX, intY, err := divValidation(x, y)
+// handleDecimal checks type of the request.
+// if type is decimal, do the calculation with decimal and return true.
+// also return true if type, scale or rounding is invalid as response has been sent.
+// return false if request should be handled as integer
+func handleDecimal(ctx *gin.Context, op ops.Operation) bool {
+	numType := ctx.DefaultQuery("type", typeInteger)
+	if numType != typeInteger && numType != typeDecimal {
+		respondError(ctx, &fieldError{field: "type", value: numType, err: errNumType})
+		return true
+	}
+	if numType != typeDecimal {
+		return false
+	}
+	if op.Rat == nil {
+		respondError(ctx, &fieldError{field: "type", value: typeDecimal, err: errUnsupported(op, typeDecimal)})
+		return true
+	}
+	scale, err := scaleValidation(ctx.DefaultQuery("scale", strconv.Itoa(defaultScale)))
 	if err != nil {
-		ctx.JSON(400, gin.H{"err": err.Error()})
-		return
+		respondError(ctx, err)
+		return true
+	}
+	rounding, err := roundingValidation(ctx.DefaultQuery("rounding", defaultRounding))
+	if err != nil {
+		respondError(ctx, err)
+		return true
+	}
+	values, list, err := getOperands(ctx, op)
+	if err != nil {
+		respondError(ctx, err)
+		return true
+	}
+	decValues, err := decimalOpValidation(op, values)
+	if err != nil {
+		respondError(ctx, namedOperand(err, list))
+		return true
 	}
-	Debug.Println("recieved:", intX, intY)
-	result, cached := getResult("div", intX, intY)
+	ttl, err := requestTTL(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return true
+	}
+	Debug.Println("recieved:", decValues)
+	result, cached, err := getDecimalResult(ttl(op.Name), op.Name, scale, rounding, decValues...)
+	if err != nil {
+		calcError(ctx, err)
+		return true
+	}
+	resp := operandFields(list, len(decValues), func(i int) interface{} { return decValues[i].String() })
+	resp["action"], resp["answer"], resp["cached"] = op.Action, result.String(), cached
+	resp["type"], resp["scale"], resp["rounding"] = typeDecimal, scale, rounding
+	respond(ctx, 200, resp)
+	return true
+}
 
-	ctx.JSON(200, gin.H{"action": "divide", "x": intX, "y": intY, "answer": result, "cached": cached})
+// handleBig checks precision of the request, either from query string or defaultPrecision.
+// if precision is big, do the calculation with big.Int and return true.
+// also return true if precision is invalid as response has been sent.
+// return false if request should be handled as int64
+func handleBig(ctx *gin.Context, op ops.Operation) bool {
+	precision := ctx.DefaultQuery("precision", defaultPrecision)
+	if validPrecision(precision) == false {
+		respondError(ctx, &fieldError{field: "precision", value: precision, err: errPrecision})
+		return true
+	}
+	if precision != precisionBig {
+		return false
+	}
+	if op.Big == nil {
+		respondError(ctx, &fieldError{field: "precision", value: precisionBig, err: errUnsupported(op, precisionBig)})
+		return true
+	}
+	values, list, err := getOperands(ctx, op)
+	if err != nil {
+		respondError(ctx, err)
+		return true
+	}
+	bigValues, err := bigOpValidation(op, values)
+	if err != nil {
+		respondError(ctx, namedOperand(err, list))
+		return true
+	}
+	ttl, err := requestTTL(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return true
+	}
+	Debug.Println("recieved:", bigValues)
+	// numbers are returned as string so that json decoder of client won't lose precision
+	resp := operandFields(list, len(bigValues), func(i int) interface{} { return bigValues[i].String() })
+	resp["action"], resp["precision"] = op.Action, precisionBig
+	if op.Name == "div" {
+		// mode has been validated by divide handler
+		mode := ctx.DefaultQuery("mode", defaultDivMode)
+		result, remainder, cached := getBigDivResult(ttl(op.Name), mode, bigValues...)
+		resp["answer"], resp["mode"], resp["cached"] = result.String(), mode, cached
+		if list == false {
+			resp["remainder"] = remainder.String()
+		}
+		respond(ctx, 200, resp)
+		return true
+	}
+	result, cached, err := getBigResult(ttl(op.Name), op.Name, bigValues...)
+	if err != nil {
+		calcError(ctx, err)
+		return true
+	}
+	resp["answer"], resp["cached"] = result.String(), cached
+	respond(ctx, 200, resp)
+	return true
+}
+
+// rationalHandler return handler of rational route group.
+// values can be fraction (1/3) or decimal number (0.25).
+// answer is exact and returned as reduced fraction.
+// if scale is set in query string, decimal rendering of answer will be returned as well
+func rationalHandler(op ops.Operation) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		values, list, err := getOperands(ctx, op)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		ratValues, err := rationalOpValidation(op, values)
+		if err != nil {
+			respondError(ctx, namedOperand(err, list))
+			return
+		}
+		scale, withDecimal := ctx.GetQuery("scale")
+		decimalScale := 0
+		if withDecimal {
+			decimalScale, err = scaleValidation(scale)
+			if err != nil {
+				respondError(ctx, err)
+				return
+			}
+		}
+		ttl, err := requestTTL(ctx)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+		Debug.Println("recieved:", ratValues)
+		result, cached, err := getRationalResult(ttl(op.Name), op.Name, ratValues...)
+		if err != nil {
+			calcError(ctx, err)
+			return
+		}
+		resp := operandFields(list, len(ratValues), func(i int) interface{} { return ratValues[i].RatString() })
+		resp["action"], resp["answer"], resp["cached"] = op.Action, result.RatString(), cached
+		resp["type"], resp["numerator"], resp["denominator"] = typeRational, result.Num().String(), result.Denom().String()
+		if withDecimal {
+			resp["decimal"] = result.FloatString(decimalScale)
+		}
+		respond(ctx, 200, resp)
+	}
+}
+
+// calcError respond error raised during calculation, like overflow.
+// input is valid, but result can not be represented,
+// so return 422 to distinguish from validation error
+func calcError(ctx *gin.Context, err error) {
+	respondProblem(ctx, problemOf(err, codeCalculation))
 }
 
 // health endpoint. return 200 and cache status
 func health(ctx *gin.Context) {
+	respond(ctx, 200, healthStatus())
+}
+
+// healthStatus return cache status, hit and size are omitted if cache is down
+func healthStatus() gin.H {
 	err := cache.Ping()
 	if err != nil {
-		ctx.JSON(200, gin.H{"cache": err.Error()})
-		return
+		return gin.H{"cache": err.Error()}
 	}
 	hit := cache.GetCounter()
 	size := cache.GetSize()
-	ctx.JSON(200, gin.H{"cache": "OK", "hit": hit, "size": size})
+	status := gin.H{"cache": "OK", "hit": hit, "size": size}
+	if e, ok := cache.(Evicter); ok {
+		status["evicted"], status["expired"] = e.GetEvictions()
+	}
+	return status
 }
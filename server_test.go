@@ -2,9 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/ThisisYang/teltechcc/cacheMe"
 	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -15,6 +19,54 @@ func performRequest(r http.Handler, method, path string) *httptest.ResponseRecor
 	return w
 }
 
+func performRequestWithBody(r http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func performRequestWithHeader(r http.Handler, method, path, key, value string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set(key, value)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// testRequestID is the request ID of every request in tests, so that problem details can be compared
+const testRequestID = "test-request"
+
+func init() {
+	newRequestID = func() string { return testRequestID }
+}
+
+// problemBody return expected problem details, instance, field and value are omitted if empty
+func problemBody(instance, code string, err error, field, value string) gin.H {
+	h := gin.H{
+		"type": "urn:teltechcc:problem:" + code, "title": problemTypes[code].title, "status": problemTypes[code].status,
+		"detail": err.Error(), "code": code, "request_id": testRequestID,
+	}
+	if instance != "" {
+		h["instance"] = instance
+	}
+	if field != "" {
+		h["field"] = field
+	}
+	if value != "" {
+		h["value"] = value
+	}
+	return h
+}
+
+// evalProblem return expected problem details of /eval, err is at position pos of expression s
+func evalProblem(instance, code string, err error, s string, pos int) gin.H {
+	h := problemBody(instance, code, fmt.Errorf("%v at position %d", err, pos), "expr", s)
+	h["pos"] = pos
+	return h
+}
+
 func TestAdd(t *testing.T) {
 	setUpLogger(false)
 	cases := []struct {
@@ -25,11 +77,11 @@ func TestAdd(t *testing.T) {
 	}{
 		{
 			name: "case fail valid", url: "/add", expStatusCode: 400,
-			expBody: gin.H{"err": errMissX.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/add", codeMissingOperand, errMissX, "x", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case fail valid", url: "/add?x=1", expStatusCode: 400,
-			expBody: gin.H{"err": errMissY.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/add?x=1", codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case uncached", url: "/add?x=1&y=3", expStatusCode: 200,
@@ -39,12 +91,20 @@ func TestAdd(t *testing.T) {
 		{
 			name: "case cached", url: "/add?x=1&y=3", expStatusCode: 200,
 			expBody: gin.H{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": true},
-			fCache:  &fakeCacheClient{val: map[string]int{"add:1:3": 4}},
+			fCache:  &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
 		},
 		{
 			name: "case cached2", url: "/add?x=3&y=1", expStatusCode: 200,
 			expBody: gin.H{"action": "add", "x": 3, "y": 1, "answer": 4, "cached": true},
-			fCache:  &fakeCacheClient{val: map[string]int{"add:1:3": 4}},
+			fCache:  &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
+		},
+		{
+			name: "case out of range", url: "/add?x=9223372036854775808&y=1", expStatusCode: 400,
+			expBody: problemBody("/add?x=9223372036854775808&y=1", codeInvalidType, errType, "x", "9223372036854775808"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case overflow", url: "/add?x=9223372036854775807&y=1", expStatusCode: 422,
+			expBody: problemBody("/add?x=9223372036854775807&y=1", codeOverflow, errOverflow, "", ""), fCache: NewFakeCache(),
 		},
 	}
 
@@ -76,11 +136,11 @@ func TestSubtract(t *testing.T) {
 	}{
 		{
 			name: "case fail valid", url: "/subtract", expStatusCode: 400,
-			expBody: gin.H{"err": errMissX.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/subtract", codeMissingOperand, errMissX, "x", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case fail valid", url: "/subtract?x=1", expStatusCode: 400,
-			expBody: gin.H{"err": errMissY.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/subtract?x=1", codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case uncached", url: "/subtract?x=1&y=3", expStatusCode: 200,
@@ -90,7 +150,11 @@ func TestSubtract(t *testing.T) {
 		{
 			name: "case cached", url: "/subtract?x=1&y=3", expStatusCode: 200,
 			expBody: gin.H{"action": "subtract", "x": 1, "y": 3, "answer": -2, "cached": true},
-			fCache:  &fakeCacheClient{val: map[string]int{"sub:1:3": -2}},
+			fCache:  &fakeCacheClient{val: map[string]string{"sub:1:3": "-2"}},
+		},
+		{
+			name: "case overflow", url: "/subtract?x=-9223372036854775808&y=1", expStatusCode: 422,
+			expBody: problemBody("/subtract?x=-9223372036854775808&y=1", codeOverflow, errOverflow, "", ""), fCache: NewFakeCache(),
 		},
 	}
 
@@ -122,11 +186,11 @@ func TestMultiply(t *testing.T) {
 	}{
 		{
 			name: "case fail valid", url: "/multiply", expStatusCode: 400,
-			expBody: gin.H{"err": errMissX.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/multiply", codeMissingOperand, errMissX, "x", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case fail valid", url: "/multiply?x=1", expStatusCode: 400,
-			expBody: gin.H{"err": errMissY.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/multiply?x=1", codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case uncached", url: "/multiply?x=1&y=3", expStatusCode: 200,
@@ -136,12 +200,16 @@ func TestMultiply(t *testing.T) {
 		{
 			name: "case cached", url: "/multiply?x=1&y=3", expStatusCode: 200,
 			expBody: gin.H{"action": "multiply", "x": 1, "y": 3, "answer": 3, "cached": true},
-			fCache:  &fakeCacheClient{val: map[string]int{"mul:1:3": 3}},
+			fCache:  &fakeCacheClient{val: map[string]string{"mul:1:3": "3"}},
 		},
 		{
 			name: "case cached 2", url: "/multiply?x=3&y=1", expStatusCode: 200,
 			expBody: gin.H{"action": "multiply", "x": 3, "y": 1, "answer": 3, "cached": true},
-			fCache:  &fakeCacheClient{val: map[string]int{"mul:1:3": 3}},
+			fCache:  &fakeCacheClient{val: map[string]string{"mul:1:3": "3"}},
+		},
+		{
+			name: "case overflow", url: "/multiply?x=4611686018427387904&y=2", expStatusCode: 422,
+			expBody: problemBody("/multiply?x=4611686018427387904&y=2", codeOverflow, errOverflow, "", ""), fCache: NewFakeCache(),
 		},
 	}
 
@@ -173,30 +241,53 @@ func TestDivide(t *testing.T) {
 	}{
 		{
 			name: "case fail valid", url: "/divide", expStatusCode: 400,
-			expBody: gin.H{"err": errMissX.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/divide", codeMissingOperand, errMissX, "x", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case fail valid", url: "/divide?x=1", expStatusCode: 400,
-			expBody: gin.H{"err": errMissY.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/divide?x=1", codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
 		},
 		{
 			name: "case divide by zero", url: "/divide?x=4&y=0", expStatusCode: 400,
-			expBody: gin.H{"err": errDivideByZero.Error()}, fCache: NewFakeCache(),
+			expBody: problemBody("/divide?x=4&y=0", codeDivideByZero, errDivideByZero, "y", "0"), fCache: NewFakeCache(),
 		},
 		{
 			name: "case uncached", url: "/divide?x=1&y=3", expStatusCode: 200,
-			expBody: gin.H{"action": "divide", "x": 1, "y": 3, "answer": 0, "cached": false},
+			expBody: gin.H{"action": "divide", "x": 1, "y": 3, "answer": 0, "remainder": 1, "mode": "floor", "cached": false},
 			fCache:  NewFakeCache(),
 		},
 		{
 			name: "case cached", url: "/divide?x=1&y=3", expStatusCode: 200,
-			expBody: gin.H{"action": "divide", "x": 1, "y": 3, "answer": 0, "cached": true},
-			fCache:  &fakeCacheClient{val: map[string]int{"div:1:3": 0}},
+			expBody: gin.H{"action": "divide", "x": 1, "y": 3, "answer": 0, "remainder": 1, "mode": "floor", "cached": true},
+			fCache:  &fakeCacheClient{val: map[string]string{"div:floor:1:3": "0"}},
 		},
 		{
 			name: "case cached 2", url: "/divide?x=3&y=1", expStatusCode: 200,
-			expBody: gin.H{"action": "divide", "x": 3, "y": 1, "answer": 3, "cached": false},
-			fCache:  &fakeCacheClient{val: map[string]int{"div:1:3": 3}},
+			expBody: gin.H{"action": "divide", "x": 3, "y": 1, "answer": 3, "remainder": 0, "mode": "floor", "cached": false},
+			fCache:  &fakeCacheClient{val: map[string]string{"div:floor:1:3": "3"}},
+		},
+		{
+			name: "case overflow", url: "/divide?x=-9223372036854775808&y=-1", expStatusCode: 422,
+			expBody: problemBody("/divide?x=-9223372036854775808&y=-1", codeOverflow, errOverflow, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case floor negative", url: "/divide?x=-1&y=2", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "x": -1, "y": 2, "answer": -1, "remainder": 1, "mode": "floor", "cached": false},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case trunc", url: "/divide?x=-1&y=2&mode=trunc", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "x": -1, "y": 2, "answer": 0, "remainder": -1, "mode": "trunc", "cached": false},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case mode not collide", url: "/divide?x=-1&y=2&mode=ceil", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "x": -1, "y": 2, "answer": 0, "remainder": -1, "mode": "ceil", "cached": false},
+			fCache:  &fakeCacheClient{val: map[string]string{"div:floor:-1:2": "-1"}},
+		},
+		{
+			name: "case invalid mode", url: "/divide?x=-1&y=2&mode=round", expStatusCode: 400,
+			expBody: problemBody("/divide?x=-1&y=2&mode=round", codeInvalidParameter, errDivMode, "mode", "round"), fCache: NewFakeCache(),
 		},
 	}
 
@@ -218,25 +309,53 @@ func TestDivide(t *testing.T) {
 	}
 }
 
+func TestHealth(t *testing.T) {
+	setUpLogger(false)
+	router := newRouter()
+	dc := cacheMe.NewDefaultClient(cacheMe.Policy{}, cacheMe.MaxEntries(1))
+	defer dc.Close()
+	cases := []struct {
+		name    string
+		fCache  cacheClient
+		expBody gin.H
+	}{
+		{name: "case fake", fCache: &fakeCacheClient{val: map[string]string{"add:1:2": "3"}, hit: 2}, expBody: gin.H{"cache": "OK", "hit": 2, "size": 1}},
+		{name: "case down", fCache: &fakeCacheClient{err: fmt.Errorf("connection refused")}, expBody: gin.H{"cache": "connection refused"}},
+		{name: "case evicted", fCache: dc, expBody: gin.H{"cache": "OK", "hit": 0, "size": 1, "evicted": 1, "expired": 0}},
+	}
+	for _, c := range cases {
+		cache = c.fCache
+		if c.fCache == dc {
+			performRequest(router, "GET", "/add?x=1&y=2")
+			performRequest(router, "GET", "/add?x=2&y=3")
+		}
+		w := performRequest(router, "GET", "/health")
+		jsonEncoded, _ := json.Marshal(c.expBody)
+		if w.Code != 200 || w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot code and body:\n %v %v \nexp code and body\n %v %v \n", c.name, w.Code, w.Body.String(), 200, string(jsonEncoded))
+		}
+	}
+}
+
 func Test405(t *testing.T) {
 	setUpLogger(false)
 	cases := []struct {
 		name, url     string
 		expStatusCode int
-		expBody       string
+		expBody       gin.H
 		method        string
 	}{
 		{
-			name: "case post", url: "/divide", expStatusCode: 405,
-			expBody: "405 method not allowed", method: "POST",
+			name: "case post", url: "/health", expStatusCode: 405,
+			expBody: problemBody("/health", codeMethodNotAllowed, errMethodNotAllowed, "method", "POST"), method: "POST",
 		},
 		{
 			name: "case delete", url: "/add", expStatusCode: 405,
-			expBody: "405 method not allowed", method: "DELETE",
+			expBody: problemBody("/add", codeMethodNotAllowed, errMethodNotAllowed, "method", "DELETE"), method: "DELETE",
 		},
 		{
 			name: "case option", url: "/subtract", expStatusCode: 405,
-			expBody: "405 method not allowed", method: "OPTION",
+			expBody: problemBody("/subtract", codeMethodNotAllowed, errMethodNotAllowed, "method", "OPTION"), method: "OPTION",
 		},
 	}
 	// setup router
@@ -248,9 +367,12 @@ func Test405(t *testing.T) {
 		if w.Code != c.expStatusCode {
 			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
 		}
-		if w.Body.String() != c.expBody {
-			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), c.expBody)
-
+		jsonEncoded, _ := json.Marshal(c.expBody)
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+		if got := w.Header().Get("Content-Type"); got != mimeProblemJSON {
+			t.Errorf("error on: %v\ngot content type:\n %v \nexp content type\n %v \n", c.name, got, mimeProblemJSON)
 		}
 	}
 }
@@ -260,16 +382,16 @@ func Test404(t *testing.T) {
 	cases := []struct {
 		name, url     string
 		expStatusCode int
-		expBody       string
+		expBody       gin.H
 		method        string
 	}{
 		{
 			name: "case get", url: "/", expStatusCode: 404,
-			expBody: "404 page not found", method: "GET",
+			expBody: problemBody("/", codeNotFound, errNotFound, "path", "/"), method: "GET",
 		},
 		{
-			name: "case post", url: "/ok", expStatusCode: 404,
-			expBody: "404 page not found", method: "POST",
+			name: "case post", url: "/ok?x=1", expStatusCode: 404,
+			expBody: problemBody("/ok?x=1", codeNotFound, errNotFound, "path", "/ok"), method: "POST",
 		},
 	}
 	// setup router
@@ -281,9 +403,622 @@ func Test404(t *testing.T) {
 		if w.Code != c.expStatusCode {
 			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
 		}
-		if w.Body.String() != c.expBody {
-			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), c.expBody)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+		if got := w.Header().Get("Content-Type"); got != mimeProblemJSON {
+			t.Errorf("error on: %v\ngot content type:\n %v \nexp content type\n %v \n", c.name, got, mimeProblemJSON)
+		}
+	}
+}
+
+func TestBigPrecision(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url        string
+		defaultPrecision string
+		expStatusCode    int
+		expBody          gin.H
+		fCache           *fakeCacheClient
+	}{
+		{
+			name: "case invalid precision", url: "/add?x=1&y=2&precision=float", defaultPrecision: precisionInt,
+			expStatusCode: 400, expBody: problemBody("/add?x=1&y=2&precision=float", codeInvalidParameter, errPrecision, "precision", "float"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case add", url: "/add?x=9223372036854775807&y=1&precision=big", defaultPrecision: precisionInt,
+			expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "add", "x": "9223372036854775807", "y": "1", "answer": "9223372036854775808", "cached": false, "precision": "big"},
+		},
+		{
+			name: "case server default", url: "/multiply?x=-3&y=99999999999999999999", defaultPrecision: precisionBig,
+			expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "multiply", "x": "-3", "y": "99999999999999999999", "answer": "-299999999999999999997", "cached": false, "precision": "big"},
+		},
+		{
+			name: "case override server default", url: "/subtract?x=1&y=3&precision=int", defaultPrecision: precisionBig,
+			expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "subtract", "x": 1, "y": 3, "answer": -2, "cached": false},
+		},
+		{
+			name: "case cached", url: "/divide?x=99999999999999999999&y=3&precision=big", defaultPrecision: precisionInt,
+			expStatusCode: 200, fCache: &fakeCacheClient{val: map[string]string{"big:div:floor:99999999999999999999:3": "33333333333333333333"}},
+			expBody: gin.H{"action": "divide", "x": "99999999999999999999", "y": "3", "answer": "33333333333333333333", "remainder": "0", "mode": "floor", "cached": true, "precision": "big"},
+		},
+		{
+			name: "case half-even", url: "/divide?x=-99999999999999999999&y=2&precision=big&mode=half-even", defaultPrecision: precisionInt,
+			expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "divide", "x": "-99999999999999999999", "y": "2", "answer": "-50000000000000000000", "remainder": "1", "mode": "half-even", "cached": false, "precision": "big"},
+		},
+		{
+			name: "case mod", url: "/mod?x=-99999999999999999999&y=7&precision=big", defaultPrecision: precisionInt,
+			expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "mod", "x": "-99999999999999999999", "y": "7", "answer": "6", "cached": false, "precision": "big"},
+		},
+		{
+			name: "case divide by zero", url: "/divide?x=99999999999999999999&y=0&precision=big", defaultPrecision: precisionInt,
+			expStatusCode: 400, expBody: problemBody("/divide?x=99999999999999999999&y=0&precision=big", codeDivideByZero, errDivideByZero, "y", "0"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case type err", url: "/add?x=1.5&y=0&precision=big", defaultPrecision: precisionInt,
+			expStatusCode: 400, expBody: problemBody("/add?x=1.5&y=0&precision=big", codeInvalidType, errBigType, "x", "1.5"), fCache: NewFakeCache(),
+		},
+	}
+
+	router := newRouter()
+	defer func() { defaultPrecision = precisionInt }()
+	for _, c := range cases {
+		cache = c.fCache
+		defaultPrecision = c.defaultPrecision
+		w := performRequest(router, "GET", c.url)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url     string
+		expStatusCode int
+		expBody       gin.H
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case invalid type", url: "/add?x=1&y=2&type=float",
+			expStatusCode: 400, expBody: problemBody("/add?x=1&y=2&type=float", codeInvalidParameter, errNumType, "type", "float"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case invalid scale", url: "/add?x=1&y=2&type=decimal&scale=-1",
+			expStatusCode: 400, expBody: problemBody("/add?x=1&y=2&type=decimal&scale=-1", codeInvalidParameter, errScale, "scale", "-1"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case invalid rounding", url: "/add?x=1&y=2&type=decimal&rounding=nearest",
+			expStatusCode: 400, expBody: problemBody("/add?x=1&y=2&type=decimal&rounding=nearest", codeInvalidParameter, errRounding, "rounding", "nearest"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case type err", url: "/add?x=1,5&y=2&type=decimal",
+			expStatusCode: 400, expBody: problemBody("/add?x=1,5&y=2&type=decimal", codeInvalidType, errDecimalType, "x", "1,5"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case divide by zero", url: "/divide?x=1.5&y=0.00&type=decimal",
+			expStatusCode: 400, expBody: problemBody("/divide?x=1.5&y=0.00&type=decimal", codeDivideByZero, errDivideByZero, "y", "0"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case add", url: "/add?x=1.50&y=0.25&type=decimal", expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "add", "x": "1.5", "y": "0.25", "answer": "1.75", "cached": false, "type": "decimal", "scale": 10, "rounding": "half-even"},
+		},
+		{
+			name: "case divide", url: "/divide?x=2&y=3&type=decimal&scale=3&rounding=down", expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "divide", "x": "2", "y": "3", "answer": "0.666", "cached": false, "type": "decimal", "scale": 3, "rounding": "down"},
+		},
+		{
+			name: "case cached", url: "/multiply?x=1.5&y=1.50&type=decimal&scale=1", expStatusCode: 200,
+			fCache:  &fakeCacheClient{val: map[string]string{"decimal:1:half-even:mul:1.5:1.5": "2.2"}},
+			expBody: gin.H{"action": "multiply", "x": "1.5", "y": "1.5", "answer": "2.2", "cached": true, "type": "decimal", "scale": 1, "rounding": "half-even"},
+		},
+		{
+			name: "case subtract", url: "/subtract?x=0.1&y=0.3&type=decimal", expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "subtract", "x": "0.1", "y": "0.3", "answer": "-0.2", "cached": false, "type": "decimal", "scale": 10, "rounding": "half-even"},
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequest(router, "GET", c.url)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+}
+
+func TestRational(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url     string
+		expStatusCode int
+		expBody       gin.H
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case miss x", url: "/rational/add?y=1/3",
+			expStatusCode: 400, expBody: problemBody("/rational/add?y=1/3", codeMissingOperand, errMissX, "x", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case type err", url: "/rational/add?x=1/3&y=1.5e3",
+			expStatusCode: 400, expBody: problemBody("/rational/add?x=1/3&y=1.5e3", codeInvalidType, errRationalType, "y", "1.5e3"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case divide by zero", url: "/rational/divide?x=1/3&y=0",
+			expStatusCode: 400, expBody: problemBody("/rational/divide?x=1/3&y=0", codeDivideByZero, errDivideByZero, "y", "0"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case invalid scale", url: "/rational/divide?x=1/3&y=2&scale=a",
+			expStatusCode: 400, expBody: problemBody("/rational/divide?x=1/3&y=2&scale=a", codeInvalidParameter, errScale, "scale", "a"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case add", url: "/rational/add?x=1/3&y=1/6", expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "add", "x": "1/3", "y": "1/6", "answer": "1/2", "cached": false, "type": "rational", "numerator": "1", "denominator": "2"},
+		},
+		{
+			name: "case integer answer", url: "/rational/multiply?x=2/3&y=3", expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "multiply", "x": "2/3", "y": "3", "answer": "2", "cached": false, "type": "rational", "numerator": "2", "denominator": "1"},
+		},
+		{
+			name: "case decimal rendering", url: "/rational/divide?x=1&y=3&scale=4", expStatusCode: 200, fCache: NewFakeCache(),
+			expBody: gin.H{"action": "divide", "x": "1", "y": "3", "answer": "1/3", "cached": false, "type": "rational", "numerator": "1", "denominator": "3", "decimal": "0.3333"},
+		},
+		{
+			name: "case cached", url: "/rational/subtract?x=2/4&y=1/3", expStatusCode: 200,
+			fCache:  &fakeCacheClient{val: map[string]string{"rational:sub:1/2:1/3": "1/6"}},
+			expBody: gin.H{"action": "subtract", "x": "1/2", "y": "1/3", "answer": "1/6", "cached": true, "type": "rational", "numerator": "1", "denominator": "6"},
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequest(router, "GET", c.url)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+}
+
+func TestModRem(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url     string
+		expStatusCode int
+		expBody       gin.H
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case mod by zero", url: "/mod?x=1&y=0", expStatusCode: 400,
+			expBody: problemBody("/mod?x=1&y=0", codeDivideByZero, errDivideByZero, "y", "0"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case rem miss y", url: "/rem?x=1", expStatusCode: 400,
+			expBody: problemBody("/rem?x=1", codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case mod", url: "/mod?x=-7&y=3", expStatusCode: 200,
+			expBody: gin.H{"action": "mod", "x": -7, "y": 3, "answer": 2, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case rem", url: "/rem?x=-7&y=3", expStatusCode: 200,
+			expBody: gin.H{"action": "rem", "x": -7, "y": 3, "answer": -1, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case mod cached", url: "/mod?x=7&y=-3", expStatusCode: 200,
+			expBody: gin.H{"action": "mod", "x": 7, "y": -3, "answer": -2, "cached": true},
+			fCache:  &fakeCacheClient{val: map[string]string{"mod:7:-3": "-2"}},
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequest(router, "GET", c.url)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+}
+
+func TestOperands(t *testing.T) {
+	setUpLogger(false)
+	maxOperands = 4
+	defer func() { maxOperands = 100 }()
+	cases := []struct {
+		name, url     string
+		expStatusCode int
+		expBody       gin.H
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case repeated v", url: "/add?v=3&v=1&v=2", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "values": []int64{3, 1, 2}, "answer": 6, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case comma separated", url: "/multiply?values=2,3,4", expStatusCode: 200,
+			expBody: gin.H{"action": "multiply", "values": []int64{2, 3, 4}, "answer": 24, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case cached sorted", url: "/add?values=3,1,2", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "values": []int64{3, 1, 2}, "answer": 6, "cached": true},
+			fCache:  &fakeCacheClient{val: map[string]string{"add:1:2:3": "6"}},
+		},
+		{
+			name: "case v and values", url: "/add?v=1&values=2,3", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "values": []int64{1, 2, 3}, "answer": 6, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case subtract left to right", url: "/subtract?values=10,3,2", expStatusCode: 200,
+			expBody: gin.H{"action": "subtract", "values": []int64{10, 3, 2}, "answer": 5, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case divide left to right", url: "/divide?values=-7,2,2", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "values": []int64{-7, 2, 2}, "answer": -2, "mode": "floor", "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case divide trunc", url: "/divide?values=-7,2,2&mode=trunc", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "values": []int64{-7, 2, 2}, "answer": -1, "mode": "trunc", "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case divide by zero", url: "/divide?values=8,2,0", expStatusCode: 400,
+			expBody: problemBody("/divide?values=8,2,0", codeDivideByZero, errDivideByZero, "values[2]", "0"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case too many", url: "/add?values=1,2,3,4,5", expStatusCode: 400,
+			expBody: problemBody("/add?values=1,2,3,4,5", codeOperandCount, errOperandCount(getOp("add")), "values", "1,2,3,4,5"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case too few", url: "/add?v=1", expStatusCode: 400,
+			expBody: problemBody("/add?v=1", codeOperandCount, errOperandCount(getOp("add")), "values", "1"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case not variadic", url: "/mod?values=7,3,2", expStatusCode: 400,
+			expBody: problemBody("/mod?values=7,3,2", codeOperandCount, errOperandCount(getOp("mod")), "values", "7,3,2"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case empty value", url: "/add?values=1,,2", expStatusCode: 400,
+			expBody: problemBody("/add?values=1,,2", codeInvalidType, errType, "values[1]", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case overflow", url: "/add?values=9223372036854775807,1,-1", expStatusCode: 422,
+			expBody: problemBody("/add?values=9223372036854775807,1,-1", codeOverflow, errOverflow, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case big", url: "/add?values=9223372036854775807,1,1&precision=big", expStatusCode: 200,
+			expBody: gin.H{
+				"action": "add", "values": []string{"9223372036854775807", "1", "1"}, "answer": "9223372036854775809",
+				"cached": false, "precision": "big",
+			},
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case decimal", url: "/add?values=0.1,0.2,0.3&type=decimal", expStatusCode: 200,
+			expBody: gin.H{
+				"action": "add", "values": []string{"0.1", "0.2", "0.3"}, "answer": "0.6", "cached": false,
+				"type": "decimal", "scale": 10, "rounding": "half-even",
+			},
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case rational", url: "/rational/add?values=1/2,1/3,1/6", expStatusCode: 200,
+			expBody: gin.H{
+				"action": "add", "values": []string{"1/2", "1/3", "1/6"}, "answer": "1", "cached": false,
+				"type": "rational", "numerator": "1", "denominator": "1",
+			},
+			fCache: NewFakeCache(),
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequest(router, "GET", c.url)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+}
 
+func TestEval(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url     string
+		expStatusCode int
+		expBody       gin.H
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case miss expr", url: "/eval", expStatusCode: 400,
+			expBody: problemBody("/eval", codeMissingOperand, errMissExpr, "expr", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case uncached", url: "/eval?expr=(2%2B3)*4-10/3", expStatusCode: 200,
+			expBody: gin.H{"action": "eval", "expr": "(2+3)*4-10/3", "answer": 17, "nodes": 4, "hit": 0},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case sub-expression cached", url: "/eval?expr=(3%2B2)*4-10/3", expStatusCode: 200,
+			expBody: gin.H{"action": "eval", "expr": "(3+2)*4-10/3", "answer": 17, "nodes": 4, "hit": 2},
+			fCache:  &fakeCacheClient{val: map[string]string{"add:2:3": "5", "div:floor:10:3": "3"}},
+		},
+		{
+			name: "case division cached by /divide", url: "/eval?expr=-7/2", expStatusCode: 200,
+			expBody: gin.H{"action": "eval", "expr": "-7/2", "answer": -4, "nodes": 1, "hit": 1},
+			fCache:  &fakeCacheClient{val: map[string]string{"div:floor:-7:2": "-4"}},
+		},
+		{
+			name: "case floor", url: "/eval?expr=-7/2", expStatusCode: 200,
+			expBody: gin.H{"action": "eval", "expr": "-7/2", "answer": -4, "nodes": 1, "hit": 0},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case neg", url: "/eval?expr=-(2*3)%255", expStatusCode: 200,
+			expBody: gin.H{"action": "eval", "expr": "-(2*3)%5", "answer": 4, "nodes": 2, "hit": 0},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case divide by zero", url: "/eval?expr=1%2B4/(2-2)", expStatusCode: 400,
+			expBody: evalProblem("/eval?expr=1%2B4/(2-2)", codeDivideByZero, errDivideByZero, "1+4/(2-2)", 3), fCache: NewFakeCache(),
+		},
+		{
+			name: "case overflow", url: "/eval?expr=1%2B9223372036854775807*1", expStatusCode: 422,
+			expBody: evalProblem("/eval?expr=1%2B9223372036854775807*1", codeOverflow, errOverflow, "1+9223372036854775807*1", 1), fCache: NewFakeCache(),
+		},
+		{
+			name: "case neg overflow", url: "/eval?expr=-(-9223372036854775808)", expStatusCode: 422,
+			expBody: evalProblem("/eval?expr=-(-9223372036854775808)", codeOverflow, errOverflow, "-(-9223372036854775808)", 0), fCache: NewFakeCache(),
+		},
+		{
+			name: "case out of range", url: "/eval?expr=2*9223372036854775808", expStatusCode: 400,
+			expBody: evalProblem("/eval?expr=2*9223372036854775808", codeInvalidType, errType, "2*9223372036854775808", 2), fCache: NewFakeCache(),
+		},
+		{
+			name: "case syntax", url: "/eval?expr=(1%2B2", expStatusCode: 400,
+			expBody: evalProblem("/eval?expr=(1%2B2", codeInvalidExpr, fmt.Errorf("expected ) to match ("), "(1+2", 4), fCache: NewFakeCache(),
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequest(router, "GET", c.url)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, body    string
+		expStatusCode int
+		expBody       interface{}
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case not array", body: `{"op": "add"}`, expStatusCode: 400,
+			expBody: problemBody("/batch", codeInvalidBody, errBatchBody, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case empty", body: `[]`, expStatusCode: 200,
+			expBody: []gin.H{}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case division cached by /divide", expStatusCode: 200,
+			body: `[{"op": "div", "x": -7, "y": 2}, {"op": "divide", "x": 7, "y": 2}]`,
+			expBody: []gin.H{
+				{"action": "divide", "x": -7, "y": 2, "answer": -4, "cached": true},
+				{"action": "divide", "x": 7, "y": 2, "answer": 3, "cached": false},
+			},
+			fCache: &fakeCacheClient{val: map[string]string{"div:floor:-7:2": "-4"}},
+		},
+		{
+			name: "case mixed", expStatusCode: 200,
+			body: `[{"op": "add", "x": 1, "y": 3}, {"op": "subtract", "x": "5", "y": 2}, {"op": "div", "x": 1, "y": 0},
+				{"op": "pow", "x": 1, "y": 2}, {"op": "mul", "x": 9223372036854775807, "y": 2}, {"op": "add", "x": 1.5, "y": 1},
+				{"op": "add", "x": 1}, 5, {"x": 1, "y": 2}]`,
+			expBody: []gin.H{
+				{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": true},
+				{"action": "subtract", "x": 5, "y": 2, "answer": 3, "cached": false},
+				problemBody("", codeDivideByZero, errDivideByZero, "y", "0"),
+				problemBody("", codeUnsupported, errUnknownOp("pow"), "op", "pow"),
+				problemBody("", codeOverflow, errOverflow, "", ""),
+				problemBody("", codeInvalidType, errType, "x", "1.5"),
+				problemBody("", codeMissingOperand, errMissY, "y", ""),
+				problemBody("", codeInvalidBody, errBatchItem, "", ""),
+				problemBody("", codeMissingOperand, errMissOp, "op", ""),
+			},
+			fCache: &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequestWithBody(router, "POST", "/batch", "application/json", c.body)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+
+	// results are cached for the next batch
+	w := performRequestWithBody(router, "POST", "/batch", "application/json", `[{"op": "sub", "x": 5, "y": 2}]`)
+	jsonEncoded, _ := json.Marshal([]gin.H{{"action": "subtract", "x": 5, "y": 2, "answer": 3, "cached": true}})
+	if w.Body.String() != string(jsonEncoded) {
+		t.Errorf("error on: cached batch\ngot body:\n %v \nexp body\n %v \n", w.Body.String(), string(jsonEncoded))
+	}
+
+	// division of batch is cached for /divide
+	cache = NewFakeCache()
+	performRequestWithBody(router, "POST", "/batch", "application/json", `[{"op": "div", "x": 7, "y": 2}]`)
+	w = performRequest(router, "GET", "/divide?x=7&y=2")
+	if strings.Contains(w.Body.String(), `"cached":true`) == false {
+		t.Errorf("error on: divide cached by batch\ngot body:\n %v \nexp cached\n", w.Body.String())
+	}
+}
+
+func msgpackBody(v interface{}) string {
+	var b []byte
+	codec.NewEncoderBytes(&b, new(codec.MsgpackHandle)).MustEncode(v)
+	return string(b)
+}
+
+func TestPostBody(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url, contentType, body string
+		expStatusCode                int
+		expBody                      gin.H
+		fCache                       *fakeCacheClient
+	}{
+		{
+			name: "case json", url: "/add", contentType: "application/json", body: `{"x": 1, "y": 3}`, expStatusCode: 200,
+			expBody: gin.H{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case json string", url: "/subtract", contentType: "application/json", body: `{"x": "1", "y": "3"}`, expStatusCode: 200,
+			expBody: gin.H{"action": "subtract", "x": 1, "y": 3, "answer": -2, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case json cached", url: "/multiply", contentType: "application/json", body: `{"x": 3, "y": 2}`, expStatusCode: 200,
+			expBody: gin.H{"action": "multiply", "x": 3, "y": 2, "answer": 6, "cached": true},
+			fCache:  &fakeCacheClient{val: map[string]string{"mul:2:3": "6"}},
+		},
+		{
+			name: "case json values", url: "/add", contentType: "application/json", body: `{"values": [1, "2", 3]}`, expStatusCode: 200,
+			expBody: gin.H{"action": "add", "values": []int64{1, 2, 3}, "answer": 6, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case json miss y", url: "/add", contentType: "application/json", body: `{"x": 1}`, expStatusCode: 400,
+			expBody: problemBody("/add", codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case json float", url: "/add", contentType: "application/json", body: `{"x": 1.5, "y": 1}`, expStatusCode: 400,
+			expBody: problemBody("/add", codeInvalidType, errType, "x", "1.5"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case json out of range", url: "/add", contentType: "application/json", body: `{"x": 9223372036854775808, "y": 1}`, expStatusCode: 400,
+			expBody: problemBody("/add", codeInvalidType, errType, "x", "9223372036854775808"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case json invalid", url: "/add", contentType: "application/json", body: `{"x": 1,`, expStatusCode: 400,
+			expBody: problemBody("/add", codeInvalidBody, errBody, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case json divide by zero", url: "/divide", contentType: "application/json", body: `{"x": 1, "y": 0}`, expStatusCode: 400,
+			expBody: problemBody("/divide", codeDivideByZero, errDivideByZero, "y", "0"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case json divide mode", url: "/divide?mode=trunc", contentType: "application/json", body: `{"x": -7, "y": 2}`, expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "x": -7, "y": 2, "answer": -3, "remainder": -1, "mode": "trunc", "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case json big", url: "/add?precision=big", contentType: "application/json", body: `{"x": 9223372036854775807, "y": 1}`, expStatusCode: 200,
+			expBody: gin.H{"action": "add", "x": "9223372036854775807", "y": "1", "answer": "9223372036854775808", "cached": false, "precision": "big"},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case form", url: "/add", contentType: "application/x-www-form-urlencoded", body: `x=1&y=3`, expStatusCode: 200,
+			expBody: gin.H{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case form values", url: "/add", contentType: "application/x-www-form-urlencoded", body: `v=1&values=2,3`, expStatusCode: 200,
+			expBody: gin.H{"action": "add", "values": []int64{1, 2, 3}, "answer": 6, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case form miss x", url: "/add", contentType: "application/x-www-form-urlencoded", body: `y=3`, expStatusCode: 400,
+			expBody: problemBody("/add", codeMissingOperand, errMissX, "x", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case xml", url: "/subtract", contentType: "application/xml", body: `<request><x>5</x><y>7</y></request>`, expStatusCode: 200,
+			expBody: gin.H{"action": "subtract", "x": 5, "y": 7, "answer": -2, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case xml values", url: "/multiply", contentType: "application/xml",
+			body: `<request><values>2</values><values>3</values><values>4</values></request>`, expStatusCode: 200,
+			expBody: gin.H{"action": "multiply", "values": []int64{2, 3, 4}, "answer": 24, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case xml type", url: "/add", contentType: "application/xml", body: `<request><x>a</x><y>7</y></request>`, expStatusCode: 400,
+			expBody: problemBody("/add", codeInvalidType, errType, "x", "a"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case msgpack", url: "/divide", contentType: "application/x-msgpack",
+			body: msgpackBody(map[string]interface{}{"x": -7, "y": 2}), expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "x": -7, "y": 2, "answer": -4, "remainder": 1, "mode": "floor", "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case msgpack string", url: "/add", contentType: "application/x-msgpack",
+			body: msgpackBody(map[string]interface{}{"x": "9223372036854775807", "y": 1}), expStatusCode: 422,
+			expBody: problemBody("/add", codeOverflow, errOverflow, "", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case msgpack values", url: "/add", contentType: "application/x-msgpack",
+			body: msgpackBody(map[string]interface{}{"values": []int64{1, 2, 3}}), expStatusCode: 200,
+			expBody: gin.H{"action": "add", "values": []int64{1, 2, 3}, "answer": 6, "cached": false}, fCache: NewFakeCache(),
+		},
+		{
+			name: "case protobuf", url: "/add", contentType: "application/x-protobuf", body: "", expStatusCode: 400,
+			expBody: problemBody("/add", codeInvalidBody, errBody, "", ""), fCache: NewFakeCache(),
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequestWithBody(router, "POST", c.url, c.contentType, c.body)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
 		}
 	}
 }
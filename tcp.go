@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLineLength is the max length of a request line of TCP protocol
+const maxLineLength = 4096
+
+// tcpIdleTimeout close connection without request for a while
+var tcpIdleTimeout = 5 * time.Minute
+
+// tcpShutdownGrace is how long connections keep reading on shutdown,
+// so that lines in flight are replied rather than cut
+var tcpShutdownGrace = time.Second
+
+var errTCPServerClosed = fmt.Errorf("tcp: Server closed")
+var errLineLength = fmt.Errorf("Line is too long, max %d characters", maxLineLength)
+var errIncompleteLine = fmt.Errorf("Incomplete line. Request has to end with newline")
+
+// tcpServer serve calculation in newline-delimited text protocol, like `ADD 2 5` -> `OK 7 CACHED`.
+// requests can be pipelined, replies are in the same order.
+// it shares validation and cache with the HTTP server
+type tcpServer struct {
+	addr     string
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    map[net.Conn]struct{}
+	closing  bool
+	wg       sync.WaitGroup
+}
+
+func newTCPServer(ip string, port int) *tcpServer {
+	return &tcpServer{addr: fmt.Sprintf("%v:%v", ip, port), conns: make(map[net.Conn]struct{})}
+}
+
+// ListenAndServe listen on addr and serve connections.
+// errTCPServerClosed is returned after Shutdown, same as http.Server
+func (s *tcpServer) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accept connections on l, each connection is served in its own goroutine
+func (s *tcpServer) Serve(l net.Listener) error {
+	s.mutex.Lock()
+	if s.closing {
+		s.mutex.Unlock()
+		l.Close()
+		return errTCPServerClosed
+	}
+	s.listener = l
+	s.mutex.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closing := s.closing
+			s.mutex.Unlock()
+			if closing {
+				return errTCPServerClosed
+			}
+			return err
+		}
+		if s.track(conn) == false {
+			conn.Close()
+			continue
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// track add conn to connections, false if server is shutting down
+func (s *tcpServer) track(conn net.Conn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closing {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *tcpServer) untrack(conn net.Conn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.conns, conn)
+	s.wg.Done()
+}
+
+// Shutdown stop accepting connections and drain the open ones:
+// lines received within tcpShutdownGrace are replied, then connections are closed.
+// a line still incomplete by then gets errIncompleteLine.
+// connections are closed forcibly if ctx is done first
+func (s *tcpServer) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.closing = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	// blocked reads return after the grace, so that connection is closed once lines in flight are replied
+	deadline := time.Now().Add(tcpShutdownGrace)
+	for conn := range s.conns {
+		conn.SetReadDeadline(deadline)
+	}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mutex.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// serveConn reply request lines of conn until it is closed, QUIT is received or server is shut down.
+// replies are flushed once there is no more pipelined request in buffer
+func (s *tcpServer) serveConn(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+	Debug.Println("tcp connection from", conn.RemoteAddr())
+
+	r := bufio.NewReaderSize(conn, maxLineLength)
+	w := bufio.NewWriter(conn)
+	defer w.Flush()
+	for {
+		// deadline set by Shutdown is kept
+		s.mutex.Lock()
+		if s.closing == false {
+			conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		}
+		s.mutex.Unlock()
+		b, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			fmt.Fprintf(w, "ERR %v %v\n", codeInvalidBody, errLineLength)
+			return
+		}
+		// line without newline is cut by EOF or deadline, rest of it may be still in flight,
+		// it is never run as a request
+		if err != nil {
+			if strings.TrimSpace(string(b)) != "" {
+				fmt.Fprintf(w, "ERR %v %v\n", codeInvalidBody, errIncompleteLine)
+			}
+			return
+		}
+		line := strings.TrimSpace(string(b))
+		if strings.EqualFold(line, "QUIT") {
+			fmt.Fprintln(w, "BYE")
+			return
+		}
+		if line != "" {
+			fmt.Fprintln(w, handleLine(line))
+		}
+		// flush once pipelined requests are replied
+		if r.Buffered() == 0 {
+			w.Flush()
+		}
+	}
+}
+
+// handleLine return reply of a request line of TCP protocol:
+//
+//	<op> <operand> <operand> ...  ->  OK <answer> [CACHED]
+//	PING                          ->  PONG
+//	HEALTH                        ->  OK <hit> <size>
+//
+// op is name or action of registered operation, case insensitive, like ADD or SUBTRACT.
+// error is replied as `ERR <code> <detail>`, code is the same as problem details
+func handleLine(line string) string {
+	fields := strings.Fields(line)
+	switch strings.ToLower(fields[0]) {
+	case "ping":
+		return "PONG"
+	case "health":
+		status := healthStatus()
+		if status["cache"] != "OK" {
+			return fmt.Sprintf("ERR %v %v", codeInternal, status["cache"])
+		}
+		return fmt.Sprintf("OK %v %v", status["hit"], status["size"])
+	}
+	result, cached, err := execCommand(fields)
+	if err != nil {
+		return tcpError(err)
+	}
+	reply := "OK " + strconv.FormatInt(result, 10)
+	if cached {
+		reply += " CACHED"
+	}
+	return reply
+}
+
+// execCommand calculate command like [ADD 2 5], shared by TCP protocol and REPL.
+// op is name or action of registered operation, case insensitive.
+// validation and cache are the same as the HTTP server, div is floor division
+func execCommand(fields []string) (int64, bool, error) {
+	op, ok := findOp(strings.ToLower(fields[0]))
+	if ok == false {
+		return 0, false, errUnknownOp(fields[0])
+	}
+	values := fields[1:]
+	err := operandsValidation(op, values)
+	if err != nil {
+		return 0, false, err
+	}
+	intValues, err := opValidation(op, values)
+	if err != nil {
+		return 0, false, err
+	}
+	return getResult(opTTL(op.Name), op.Name, intValues...)
+}
+
+// tcpError return error reply of err, detail is single line
+func tcpError(err error) string {
+	return fmt.Sprintf("ERR %v %v", errorCode(err, codeCalculation), err)
+}
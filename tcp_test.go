@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleLine(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, line, exp string
+		fCache          *fakeCacheClient
+	}{
+		{name: "case add", line: "ADD 2 5", exp: "OK 7", fCache: NewFakeCache()},
+		{name: "case cached", line: "add 5 2", exp: "OK 7 CACHED", fCache: &fakeCacheClient{val: map[string]string{"add:2:5": "7"}}},
+		{name: "case action", line: "Subtract 10 3 2", exp: "OK 5", fCache: NewFakeCache()},
+		{name: "case divide", line: "DIV -7 2", exp: "OK -4", fCache: NewFakeCache()},
+		{name: "case ping", line: "PING", exp: "PONG", fCache: NewFakeCache()},
+		{name: "case health", line: "health", exp: "OK 3 1", fCache: &fakeCacheClient{val: map[string]string{"add:2:5": "7"}, hit: 3}},
+		{name: "case unknown op", line: "POW 2 5", exp: "ERR unsupported " + errUnknownOp("POW").Error(), fCache: NewFakeCache()},
+		{name: "case missing operand", line: "MUL 2", exp: "ERR operand_count " + errOperandCount(getOp("mul")).Error(), fCache: NewFakeCache()},
+		{name: "case invalid type", line: "ADD 2 x", exp: "ERR invalid_type " + errType.Error(), fCache: NewFakeCache()},
+		{name: "case divide by zero", line: "DIV 2 0", exp: "ERR divide_by_zero " + errDivideByZero.Error(), fCache: NewFakeCache()},
+		{name: "case overflow", line: "MUL 9223372036854775807 2", exp: "ERR overflow " + errOverflow.Error(), fCache: NewFakeCache()},
+	}
+	for _, c := range cases {
+		cache = c.fCache
+		got := handleLine(c.line)
+		if got != c.exp {
+			t.Errorf("error on: %v\ngot reply:\n %v \nexp reply\n %v \n", c.name, got, c.exp)
+		}
+	}
+}
+
+// startTCPServer serve on a random local port, address is returned
+func startTCPServer(t *testing.T) (*tcpServer, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTCPServer("127.0.0.1", 0)
+	go s.Serve(l)
+	return s, l.Addr().String()
+}
+
+// readReplies read n reply lines from conn
+func readReplies(t *testing.T, r *bufio.Reader, n int) []string {
+	replies := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read reply %d: %v", i, err)
+		}
+		replies = append(replies, strings.TrimSuffix(line, "\n"))
+	}
+	return replies
+}
+
+func TestTCPServer(t *testing.T) {
+	setUpLogger(false)
+	cache = NewFakeCache()
+	s, addr := startTCPServer(t)
+	defer s.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// pipelined requests are replied in order, error does not close connection
+	fmt.Fprint(conn, "ADD 2 5\nADD 5 2\n\nADD 2\nSUB 9 4\n")
+	exp := []string{"OK 7", "OK 7 CACHED", "ERR operand_count " + errOperandCount(getOp("add")).Error(), "OK 5"}
+	got := readReplies(t, r, len(exp))
+	if strings.Join(got, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("error on: case pipelining\ngot replies:\n %v \nexp replies\n %v \n", got, exp)
+	}
+
+	fmt.Fprint(conn, "QUIT\n")
+	got = readReplies(t, r, 1)
+	if got[0] != "BYE" {
+		t.Errorf("error on: case quit\ngot reply:\n %v \nexp reply\n %v \n", got[0], "BYE")
+	}
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Errorf("error on: case quit\nconnection is not closed")
+	}
+}
+
+func TestTCPLineLength(t *testing.T) {
+	setUpLogger(false)
+	cache = NewFakeCache()
+	s, addr := startTCPServer(t)
+	defer s.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	fmt.Fprint(conn, "ADD "+strings.Repeat("1", maxLineLength)+"\n")
+	exp := fmt.Sprintf("ERR %v %v", codeInvalidBody, errLineLength)
+	got := readReplies(t, r, 1)
+	if got[0] != exp {
+		t.Errorf("error on: case line length\ngot reply:\n %v \nexp reply\n %v \n", got[0], exp)
+	}
+}
+
+func TestTCPIncompleteLine(t *testing.T) {
+	setUpLogger(false)
+	cache = NewFakeCache()
+	timeout := tcpIdleTimeout
+	tcpIdleTimeout = 50 * time.Millisecond
+	defer func() { tcpIdleTimeout = timeout }()
+	s, addr := startTCPServer(t)
+	defer s.Shutdown(context.Background())
+	exp := fmt.Sprintf("ERR %v %v", codeInvalidBody, errIncompleteLine)
+
+	cases := []struct {
+		name string
+		line string
+		exp  string
+		// closeWrite half-closes the connection, otherwise line is cut by idle timeout
+		closeWrite bool
+	}{
+		{name: "case eof", line: "ADD 2 5\nADD 2", exp: "OK 7", closeWrite: true},
+		{name: "case timeout", line: "ADD 3 5\nADD 3", exp: "OK 8"},
+	}
+	for _, c := range cases {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, c.line)
+		if c.closeWrite {
+			conn.(*net.TCPConn).CloseWrite()
+		}
+		got := readReplies(t, r, 2)
+		if got[0] != c.exp || got[1] != exp {
+			t.Errorf("error on: %v\ngot replies:\n %v \nexp replies\n %v \n", c.name, got, []string{c.exp, exp})
+		}
+		if _, err := r.ReadString('\n'); err == nil {
+			t.Errorf("error on: %v\nconnection is not closed", c.name)
+		}
+		conn.Close()
+	}
+}
+
+func TestTCPShutdown(t *testing.T) {
+	setUpLogger(false)
+	cache = NewFakeCache()
+	grace := tcpShutdownGrace
+	tcpShutdownGrace = 200 * time.Millisecond
+	defer func() { tcpShutdownGrace = grace }()
+	s, addr := startTCPServer(t)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "PING\n")
+	readReplies(t, r, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error)
+	go func() { shutdown <- s.Shutdown(ctx) }()
+	for closing := false; closing == false; {
+		s.mutex.Lock()
+		closing = s.closing
+		s.mutex.Unlock()
+	}
+	// line sent during shutdown is replied within the grace
+	fmt.Fprint(conn, "PING\n")
+	replies := readReplies(t, r, 1)
+	if replies[0] != "PONG" {
+		t.Errorf("error on: case shutdown\ngot reply:\n %v \nexp reply\n %v \n", replies[0], "PONG")
+	}
+	err = <-shutdown
+	if err != nil {
+		t.Errorf("error on: case shutdown\ngot err:\n %v \nexp err\n %v \n", err, nil)
+	}
+	// idle connection is closed once shut down
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Errorf("error on: case shutdown\nconnection is not closed")
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Errorf("error on: case shutdown\nnew connection is accepted")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMaxAge is the max of max-age in Cache-Control of a request, one year in seconds
+const maxMaxAge = 365 * 24 * 60 * 60
+
+// noCache is the TTL of a request whose result is neither read from nor written to cache,
+// like Cache-Control: max-age=0 or no-cache. 0 TTL is no expiry
+const noCache time.Duration = -1
+
+// defaultTTL is how long result is cached, renewed on every hit. 0 is no expiry.
+// can be changed via --ttl flag
+var defaultTTL = time.Minute
+
+// opTTLs are TTL of operations overriding defaultTTL, keyed by op name.
+// can be set via --op-ttl flag
+var opTTLs = map[string]time.Duration{}
+
+var errTTL = fmt.Errorf("Invalid ttl. Duration no less than 0 only, 0 is no expiry")
+var errOpTTL = fmt.Errorf("Invalid op ttl. Comma separated op=duration only, for example: add=10m,div=0")
+var errMaxAge = fmt.Errorf("Invalid max-age of Cache-Control. Integer between 0 and %d only", maxMaxAge)
+
+// opTTL return TTL of result of op
+func opTTL(name string) time.Duration {
+	if ttl, ok := opTTLs[name]; ok {
+		return ttl
+	}
+	return defaultTTL
+}
+
+// parseOpTTLs parse --op-ttl, like add=10m,div=0.
+// op can be name or action, like mul or multiply
+func parseOpTTLs(s string) (map[string]time.Duration, error) {
+	ttls := map[string]time.Duration{}
+	if s == "" {
+		return ttls, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%w. %q is not op=duration", errOpTTL, pair)
+		}
+		op, ok := findOp(kv[0])
+		if ok == false {
+			return nil, fmt.Errorf("%w. %v", errOpTTL, errUnknownOp(kv[0]))
+		}
+		ttl, err := time.ParseDuration(kv[1])
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("%w. %v of %v", errOpTTL, errTTL, op.Name)
+		}
+		ttls[op.Name] = ttl
+	}
+	return ttls, nil
+}
+
+// requestTTL return TTL of each op for the request.
+// max-age of Cache-Control overrides TTL of all ops, max-age=0, no-cache and no-store are noCache.
+// other directives are ignored
+func requestTTL(ctx *gin.Context) (func(name string) time.Duration, error) {
+	header := ctx.GetHeader("Cache-Control")
+	if header == "" {
+		return opTTL, nil
+	}
+	override, ok := time.Duration(0), false
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return func(string) time.Duration { return noCache }, nil
+		case strings.HasPrefix(directive, "max-age="):
+			age, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(directive, "max-age="), `"`))
+			if err != nil || age < 0 || age > maxMaxAge {
+				return nil, &fieldError{field: "Cache-Control", value: header, err: errMaxAge}
+			}
+			override, ok = time.Duration(age)*time.Second, true
+			if age == 0 {
+				override = noCache
+			}
+		}
+	}
+	if ok == false {
+		return opTTL, nil
+	}
+	return func(string) time.Duration { return override }, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOpTTLs(t *testing.T) {
+	cases := []struct {
+		name, s string
+		exp     map[string]time.Duration
+		expErr  error
+	}{
+		{name: "case empty", s: "", exp: map[string]time.Duration{}},
+		{name: "case ok", s: "add=10m, multiply=0,div=1h30m", exp: map[string]time.Duration{"add": 10 * time.Minute, "mul": 0, "div": 90 * time.Minute}},
+		{name: "case no duration", s: "add", expErr: errOpTTL},
+		{name: "case unknown op", s: "pow=1m", expErr: errOpTTL},
+		{name: "case invalid duration", s: "add=10", expErr: errOpTTL},
+		{name: "case negative", s: "add=-1s", expErr: errOpTTL},
+	}
+	for _, c := range cases {
+		got, gotErr := parseOpTTLs(c.s)
+		if errors.Is(gotErr, c.expErr) == false || (c.expErr == nil && gotErr != nil) {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+		if c.expErr == nil && reflect.DeepEqual(got, c.exp) == false {
+			t.Errorf("error on: %v\ngot ttls:\n %v \nexp ttls\n %v \n", c.name, got, c.exp)
+		}
+	}
+}
+
+func TestOpTTL(t *testing.T) {
+	ttls := opTTLs
+	opTTLs = map[string]time.Duration{"mul": 0}
+	defer func() { opTTLs = ttls }()
+	if got := opTTL("mul"); got != 0 {
+		t.Errorf("error on: case override\ngot ttl:\n %v \nexp ttl\n %v \n", got, 0)
+	}
+	if got := opTTL("add"); got != defaultTTL {
+		t.Errorf("error on: case default\ngot ttl:\n %v \nexp ttl\n %v \n", got, defaultTTL)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	setUpLogger(false)
+	ttls := opTTLs
+	opTTLs = map[string]time.Duration{"mul": 0}
+	defer func() { opTTLs = ttls }()
+
+	cases := []struct {
+		name, url, header string
+		// body is sent by POST if it is not empty
+		body          string
+		expStatusCode int
+		expBody       interface{}
+		fCache        *fakeCacheClient
+		// expTTL is TTL of key set or renewed, nil if cache is not used
+		expTTL map[string]time.Duration
+	}{
+		{
+			name: "case default", url: "/add?x=1&y=3", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": false},
+			fCache:  NewFakeCache(), expTTL: map[string]time.Duration{"add:1:3": defaultTTL},
+		},
+		{
+			name: "case op", url: "/multiply?x=2&y=3", expStatusCode: 200,
+			expBody: gin.H{"action": "multiply", "x": 2, "y": 3, "answer": 6, "cached": false},
+			fCache:  NewFakeCache(), expTTL: map[string]time.Duration{"mul:2:3": 0},
+		},
+		{
+			name: "case max-age", url: "/multiply?x=2&y=3", header: "public, max-age=300", expStatusCode: 200,
+			expBody: gin.H{"action": "multiply", "x": 2, "y": 3, "answer": 6, "cached": true},
+			fCache:  &fakeCacheClient{val: map[string]string{"mul:2:3": "6"}}, expTTL: map[string]time.Duration{"mul:2:3": 5 * time.Minute},
+		},
+		{
+			name: "case eval", url: "/eval?expr=2*3%2B1", header: "max-age=10", expStatusCode: 200,
+			expBody: gin.H{"action": "eval", "expr": "2*3+1", "answer": 7, "nodes": 2, "hit": 0},
+			fCache:  NewFakeCache(), expTTL: map[string]time.Duration{"mul:2:3": 10 * time.Second, "add:1:6": 10 * time.Second},
+		},
+		{
+			name: "case max-age=0", url: "/add?x=1&y=3", header: "max-age=0", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": false},
+			fCache:  &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
+		},
+		{
+			name: "case no-cache", url: "/divide?x=7&y=2&type=decimal&scale=1", header: "no-cache", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "x": "7", "y": "2", "answer": "3.5", "cached": false, "type": typeDecimal, "scale": 1, "rounding": roundHalfEven},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case invalid max-age", url: "/add?x=1&y=3", header: "max-age=-1", expStatusCode: 400,
+			expBody: problemBody("/add?x=1&y=3", codeInvalidParameter, errMaxAge, "Cache-Control", "max-age=-1"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case batch max-age", url: "/batch", header: "max-age=10", body: `[{"op": "add", "x": 1, "y": 3}, {"op": "mul", "x": 2, "y": 3}]`,
+			expStatusCode: 200,
+			expBody: []gin.H{
+				{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": true},
+				{"action": "multiply", "x": 2, "y": 3, "answer": 6, "cached": false},
+			},
+			fCache: &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
+			expTTL: map[string]time.Duration{"add:1:3": 10 * time.Second, "mul:2:3": 10 * time.Second},
+		},
+		{
+			name: "case batch no-cache", url: "/batch", header: "no-cache", body: `[{"op": "add", "x": 1, "y": 3}]`, expStatusCode: 200,
+			expBody: []gin.H{{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": false}},
+			fCache:  &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
+		},
+		{
+			name: "case batch invalid max-age", url: "/batch", header: "max-age=x", body: `[{"op": "add", "x": 1, "y": 3}]`, expStatusCode: 400,
+			expBody: problemBody("/batch", codeInvalidParameter, errMaxAge, "Cache-Control", "max-age=x"), fCache: NewFakeCache(),
+		},
+		{
+			name: "case rpc max-age", url: "/rpc", header: "max-age=10", body: `{"jsonrpc": "2.0", "method": "multiply", "params": [2, 3], "id": 1}`,
+			expStatusCode: 200,
+			expBody:       gin.H{"jsonrpc": jsonRPCVersion, "id": 1, "result": gin.H{"action": "multiply", "x": 2, "y": 3, "answer": 6, "cached": false}},
+			fCache:        NewFakeCache(), expTTL: map[string]time.Duration{"mul:2:3": 10 * time.Second},
+		},
+		{
+			name: "case rpc no-store", url: "/rpc", header: "no-store", body: `{"jsonrpc": "2.0", "method": "add", "params": [1, 3], "id": 1}`,
+			expStatusCode: 200,
+			expBody:       gin.H{"jsonrpc": jsonRPCVersion, "id": 1, "result": gin.H{"action": "add", "x": 1, "y": 3, "answer": 4, "cached": false}},
+			fCache:        &fakeCacheClient{val: map[string]string{"add:1:3": "4"}},
+		},
+		{
+			name: "case rpc invalid max-age", url: "/rpc", header: "max-age=-1", body: `{"jsonrpc": "2.0", "method": "add", "params": [1, 3], "id": 1}`,
+			expStatusCode: 400,
+			expBody:       problemBody("/rpc", codeInvalidParameter, errMaxAge, "Cache-Control", "max-age=-1"), fCache: NewFakeCache(),
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		method, body := "GET", io.Reader(nil)
+		if c.body != "" {
+			method, body = "POST", strings.NewReader(c.body)
+		}
+		req, _ := http.NewRequest(method, c.url, body)
+		req.Header.Set("Cache-Control", c.header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+		if reflect.DeepEqual(c.fCache.ttl, c.expTTL) == false {
+			t.Errorf("error on: %v\ngot ttl:\n %v \nexp ttl\n %v \n", c.name, c.fCache.ttl, c.expTTL)
+		}
+	}
+}
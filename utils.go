@@ -2,71 +2,131 @@ package main
 
 import (
 	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
+	"sort"
+	"strconv"
+	"time"
 )
 
-// genCacheKey generate key of cache in format of `func:v1:v2`
-// for add and multiply operation, x and y are interchangeable
-// in this case, x and y will be sorted first.
-// 1 + 2 and 2 + 1 will have the same key: add:1:2
-// when doing subtract and divide, x and y will not be sorted
-func genCacheKey(f string, v1, v2 int) string {
-	switch f {
-	case "add":
-		return genSortedCacheKey(f, v1, v2)
-	case "div":
-		return genUnSortedCacheKey(f, v1, v2)
-	case "mul":
-		return genSortedCacheKey(f, v1, v2)
-	case "sub":
-		return genUnSortedCacheKey(f, v1, v2)
-	default:
+var errOverflow = ops.ErrOverflow
+
+// getOp return registered operation by name
+// panic if not found, name is expected to be one of the registered operations
+func getOp(f string) ops.Operation {
+	op, ok := ops.Get(f)
+	if ok == false {
 		panic(fmt.Sprintf("invalid cache key prefix %v", f))
 	}
+	return op
 }
 
-// for subtract and divide, order of the query string matters
-// x - y != y - x, x / y != y / x
-func genSortedCacheKey(f string, v1, v2 int) string {
-	if v1 > v2 {
-		v1, v2 = v2, v1
+// genCacheKey generate key of cache in format of `func:v1:v2:...`
+// for commutative operation like add and multiply, values are interchangeable
+// in this case, values will be sorted first.
+// 1 + 2 and 2 + 1 will have the same key: add:1:2
+// when doing subtract and divide, values will not be sorted.
+// division shares cache with /divide of defaultDivMode, see genDivCacheKey
+func genCacheKey(f string, values ...int64) string {
+	if f == "div" {
+		return genDivCacheKey(defaultDivMode, values...)
+	}
+	if getOp(f).Commutative {
+		return genSortedCacheKey(f, values...)
 	}
-	return genUnSortedCacheKey(f, v1, v2)
+	keys := make([]interface{}, len(values))
+	for i, v := range values {
+		keys[i] = v
+	}
+	return genUnSortedCacheKey(f, keys...)
 }
 
-// for add, multiply calculation, order of x, y doesn't matter.
-// x + y == y + x , x * y == y * x
-func genUnSortedCacheKey(f string, v1, v2 int) string {
-	return fmt.Sprintf("%v:%v:%v", f, v1, v2)
+// for add, multiply calculation, order of values doesn't matter.
+// x + y == y + x , x * y * z == z * y * x
+func genSortedCacheKey(f string, values ...int64) string {
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	keys := make([]interface{}, len(sorted))
+	for i, v := range sorted {
+		keys[i] = v
+	}
+	return genUnSortedCacheKey(f, keys...)
 }
 
-// if not cached, do the calculation
-func calculate(f string, v1, v2 int) int {
-	switch f {
-	case "add":
-		return v1 + v2
-	case "div":
-		return v1 / v2
-	case "mul":
-		return v1 * v2
-	case "sub":
-		return v1 - v2
-	default:
-		panic(fmt.Sprintf("invalid cache key prefix %v", f))
+// for subtract and divide, order of the query string matters
+// x - y != y - x, x / y != y / x
+func genUnSortedCacheKey(f string, values ...interface{}) string {
+	key := f
+	for _, v := range values {
+		key += fmt.Sprintf(":%v", v)
 	}
+	return key
+}
+
+// if not cached, do the calculation with Int of the registered operation
+// errOverflow will be returned if result is beyond int64 range.
+// division is in defaultDivMode, same as its cache key
+func calculate(f string, values ...int64) (int64, error) {
+	if f == "div" {
+		return divideWithMode(defaultDivMode, values...)
+	}
+	return getOp(f).Int(values)
 }
 
 // getResult will check the cache first
-// if exist in cache, renew TTL and return value and true
-// otherwise, do the calculation and set the set with TTL
+// if exist in cache, renew TTL to ttl and return value and true
+// otherwise, do the calculation and set the set with ttl
 // return value and false
-func getResult(f string, x, y int) (int, bool) {
-	cacheKey := genCacheKey(f, x, y)
-	result, cached := cache.Get(cacheKey)
+// if calculation failed (overflow), error is returned and nothing is cached
+func getResult(ttl time.Duration, f string, values ...int64) (int64, bool, error) {
+	cacheKey := genCacheKey(f, values...)
+	e := newCalcEvent(f, typeInteger, len(values), func(i int) interface{} { return values[i] })
+	val, cached, err := cachedResult(cacheKey, ttl, e, func() (string, error) {
+		result, err := calculate(f, values...)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(result, 10), nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	result, err := stringToInt(val)
+	if err != nil {
+		// cached value is not a valid int64, should never happen.
+		// do the calculation instead of trusting the cache
+		Warning.Printf("invalid cached value %v of key %v\n", val, cacheKey)
+		result, err = calculate(f, values...)
+		return result, false, err
+	}
+	return result, cached, nil
+}
+
+// cachedResult is shared by all numeric types.
+// it will return cached value of key and true if exist
+// otherwise, call compute and cache the result, return result and false
+// error returned by compute will not be cached.
+// ttl is TTL of key, cache is not used at all if it is noCache.
+// e is published to /events with the result
+func cachedResult(key string, ttl time.Duration, e calcEvent, compute func() (string, error)) (string, bool, error) {
+	start := time.Now()
+	var result string
+	var cached bool
+	if ttl != noCache {
+		result, cached = cache.Get(key, ttl)
+	}
 	if cached {
 		cache.IncrCounter()
-		return result, cached
+		publishCalc(e, start, result, true, nil)
+		return result, cached, nil
+	}
+	result, err := compute()
+	publishCalc(e, start, result, false, err)
+	if err != nil {
+		return "", false, err
+	}
+	if ttl != noCache {
+		cache.SetWithTTL(key, result, ttl)
 	}
-	result = calculate(f, x, y)
-	cache.SetWithTTL(cacheKey, result)
-	return result, cached
+	return result, false, nil
 }
@@ -1,22 +1,26 @@
 package main
 
 import (
+	"math"
 	"testing"
+	"time"
 )
 
 func TestGenCacheKey(t *testing.T) {
 	cases := []struct {
 		name, f, expected string
-		v1, v2            int
+		v1, v2            int64
 	}{
 		{name: "case 1", f: "add", v1: 4, v2: 0, expected: "add:0:4"},
 		{name: "case 2", f: "add", v1: 0, v2: 4, expected: "add:0:4"},
-		{name: "case 3", f: "div", v1: 1, v2: 4, expected: "div:1:4"},
-		{name: "case 4", f: "div", v1: 4, v2: 1, expected: "div:4:1"},
+		{name: "case 3", f: "div", v1: 1, v2: 4, expected: "div:floor:1:4"},
+		{name: "case 4", f: "div", v1: 4, v2: 1, expected: "div:floor:4:1"},
 		{name: "case 5", f: "mul", v1: 4, v2: 0, expected: "mul:0:4"},
 		{name: "case 6", f: "mul", v1: 0, v2: 4, expected: "mul:0:4"},
 		{name: "case 7", f: "sub", v1: 4, v2: 0, expected: "sub:4:0"},
 		{name: "case 8", f: "sub", v1: 0, v2: 4, expected: "sub:0:4"},
+		{name: "case 9", f: "mod", v1: 4, v2: 3, expected: "mod:4:3"},
+		{name: "case 10", f: "rem", v1: 3, v2: 4, expected: "rem:3:4"},
 	}
 	for _, c := range cases {
 		got := genCacheKey(c.f, c.v1, c.v2)
@@ -26,54 +30,129 @@ func TestGenCacheKey(t *testing.T) {
 	}
 }
 
+func TestGenCacheKeyValues(t *testing.T) {
+	cases := []struct {
+		name, f, expected string
+		values            []int64
+	}{
+		{name: "case sorted", f: "add", values: []int64{3, -1, 2}, expected: "add:-1:2:3"},
+		{name: "case sorted duplicated", f: "mul", values: []int64{2, 1, 2, 1}, expected: "mul:1:1:2:2"},
+		{name: "case not sorted", f: "sub", values: []int64{3, -1, 2}, expected: "sub:3:-1:2"},
+		{name: "case div not sorted", f: "div", values: []int64{8, 4, 2}, expected: "div:floor:8:4:2"},
+	}
+	for _, c := range cases {
+		got := genCacheKey(c.f, c.values...)
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestCalculateValues(t *testing.T) {
+	cases := []struct {
+		name, f  string
+		values   []int64
+		expected int64
+		expErr   error
+	}{
+		{name: "case add", f: "add", values: []int64{1, 2, 3, 4}, expected: 10},
+		{name: "case sub left to right", f: "sub", values: []int64{10, 3, 2}, expected: 5},
+		{name: "case mul", f: "mul", values: []int64{2, 3, 4}, expected: 24},
+		{name: "case div floored every step", f: "div", values: []int64{7, 2, 2}, expected: 1},
+		{name: "case div negative", f: "div", values: []int64{-7, 2, 2}, expected: -2},
+		{name: "case add overflow in the middle", f: "add", values: []int64{math.MaxInt64, 1, -1}, expErr: errOverflow},
+	}
+	for _, c := range cases {
+		got, gotErr := calculate(c.f, c.values...)
+		if got != c.expected {
+			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
+		}
+		if gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+	}
+}
+
 func TestCalculate(t *testing.T) {
 	cases := []struct {
 		name, f          string
-		v1, v2, expected int
+		v1, v2, expected int64
+		expErr           error
 	}{
 		{name: "case 1", f: "add", v1: 4, v2: 0, expected: 4},
 		{name: "case 2", f: "div", v1: 8, v2: 3, expected: 2},
 		{name: "case 3", f: "mul", v1: 1, v2: 4, expected: 4},
 		{name: "case 4", f: "sub", v1: 4, v2: 1, expected: 3},
+		{name: "case add max", f: "add", v1: math.MaxInt64 - 1, v2: 1, expected: math.MaxInt64},
+		{name: "case add overflow", f: "add", v1: math.MaxInt64, v2: 1, expErr: errOverflow},
+		{name: "case add underflow", f: "add", v1: math.MinInt64, v2: -1, expErr: errOverflow},
+		{name: "case sub min", f: "sub", v1: -1, v2: math.MaxInt64, expected: math.MinInt64},
+		{name: "case sub overflow", f: "sub", v1: math.MaxInt64, v2: -1, expErr: errOverflow},
+		{name: "case sub underflow", f: "sub", v1: math.MinInt64, v2: 1, expErr: errOverflow},
+		{name: "case mul min", f: "mul", v1: math.MinInt64, v2: 1, expected: math.MinInt64},
+		{name: "case mul overflow", f: "mul", v1: math.MaxInt64, v2: 2, expErr: errOverflow},
+		{name: "case mul min * -1", f: "mul", v1: math.MinInt64, v2: -1, expErr: errOverflow},
+		{name: "case mul -1 * min", f: "mul", v1: -1, v2: math.MinInt64, expErr: errOverflow},
+		{name: "case mul zero", f: "mul", v1: math.MinInt64, v2: 0, expected: 0},
+		{name: "case div min / -1", f: "div", v1: math.MinInt64, v2: -1, expErr: errOverflow},
+		{name: "case div min / 1", f: "div", v1: math.MinInt64, v2: 1, expected: math.MinInt64},
+		{name: "case div floor", f: "div", v1: -7, v2: 2, expected: -4},
+		{name: "case mod", f: "mod", v1: -7, v2: 3, expected: 2},
+		{name: "case rem", f: "rem", v1: -7, v2: 3, expected: -1},
+		{name: "case mod min / -1", f: "mod", v1: math.MinInt64, v2: -1, expected: 0},
 	}
 	for _, c := range cases {
-		got := calculate(c.f, c.v1, c.v2)
+		got, gotErr := calculate(c.f, c.v1, c.v2)
 		if got != c.expected {
 			t.Errorf("error on: %v\ngot:\n %v \nexpected\n %v \n", c.name, got, c.expected)
 		}
+		if gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
 	}
 }
 
 func TestGetResult(t *testing.T) {
 	cases := []struct {
 		f, name      string
-		x, y, expInt int
+		x, y, expInt int64
 		expBool      bool
+		expErr       error
 		fCache       *fakeCacheClient
 	}{
 		{
 			name: "case 1", f: "add", x: 4, y: 0, expInt: 4,
-			fCache: &fakeCacheClient{val: make(map[string]int)}, expBool: false,
+			fCache: &fakeCacheClient{val: make(map[string]string)}, expBool: false,
 		},
 		{
 			name: "case 2", f: "add", x: 4, y: 0, expInt: 4,
-			fCache: &fakeCacheClient{val: map[string]int{"add:0:4": 4}}, expBool: true,
+			fCache: &fakeCacheClient{val: map[string]string{"add:0:4": "4"}}, expBool: true,
 		},
 		{
 			name: "case 3", f: "div", x: 3, y: 3, expInt: 1,
-			fCache: &fakeCacheClient{val: map[string]int{"add:3:3": 6}}, expBool: false,
+			fCache: &fakeCacheClient{val: map[string]string{"add:3:3": "6"}}, expBool: false,
+		},
+		{
+			name: "case overflow", f: "add", x: math.MaxInt64, y: 1, expInt: 0,
+			fCache: &fakeCacheClient{val: make(map[string]string)}, expBool: false, expErr: errOverflow,
 		},
 	}
 	for _, c := range cases {
 		// overwrite cache global variable
 		cache = c.fCache
 
-		gotInt, gotBool := getResult(c.f, c.x, c.y)
+		gotInt, gotBool, gotErr := getResult(time.Minute, c.f, c.x, c.y)
 		if gotInt != c.expInt {
 			t.Errorf("error on: %v\ngot int:\n %v \nexp int\n %v \n", c.name, gotInt, c.expInt)
 		}
 		if gotBool != c.expBool {
 			t.Errorf("error on: %v\ngot bool:\n %v \nexp bool\n %v \n", c.name, gotBool, c.expBool)
 		}
+		if gotErr != c.expErr {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+		if c.expErr != nil && c.fCache.GetSize() != 0 {
+			t.Errorf("error on: %v\nfailed result should not be cached, got cache: %v\n", c.name, c.fCache.val)
+		}
 	}
 }
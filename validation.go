@@ -2,70 +2,171 @@ package main
 
 import (
 	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
+	"math"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
-var errType = fmt.Errorf("Unsupported data type. Integer only")
+var errType = fmt.Errorf("Unsupported data type. Integer between %d and %d only", int64(math.MinInt64), int64(math.MaxInt64))
 var errMissX = fmt.Errorf("x is not provided")
 var errMissY = fmt.Errorf("y is not provided")
-var errDivideByZero = fmt.Errorf("Divide by zero")
+var errDivideByZero = ops.ErrDivideByZero
+var errBigType = fmt.Errorf("Unsupported data type. Integer only")
+var errDecimalType = fmt.Errorf("Unsupported data type. Decimal number only, for example: -1.25")
+var errRationalType = fmt.Errorf("Unsupported data type. Fraction or decimal number only, for example: -1/3 or 0.25")
 
-// validation for add operation
-func addValidation(x, y string) (int, int, error) {
-	return baseValidation(x, y)
-}
+var errUnsupportedType = fmt.Errorf("Unsupported type")
 
-// validation for subtract operation
-func subValidation(x, y string) (int, int, error) {
-	return baseValidation(x, y)
+// errUnsupported is returned if op doesn't support numeric type t
+func errUnsupported(op ops.Operation, t string) error {
+	return fmt.Errorf("%w. %v does not support %v", errUnsupportedType, op.Action, t)
 }
 
-// validation for multiply operation
-func mulValidation(x, y string) (int, int, error) {
-	return baseValidation(x, y)
-}
+// maxOperands is the limit of values of variadic operation in a single request.
+// can be changed via --max-operands flag
+var maxOperands = 100
 
-// validation for divide operation
-func divValidation(x, y string) (int, int, error) {
-	intX, intY, err := baseValidation(x, y)
-	if err != nil {
-		return 0, 0, err
+var errMaxOperands = fmt.Errorf("Invalid max operands. Integer no less than 2 only")
+var errOperands = fmt.Errorf("Invalid number of operands")
+
+// errOperandCount is returned if number of values is not accepted by op
+func errOperandCount(op ops.Operation) error {
+	if op.Variadic == false {
+		return fmt.Errorf("%w. %v accepts %d operands only", errOperands, op.Action, op.Arity)
 	}
-	if intY == 0 {
-		return intX, intY, errDivideByZero
+	return fmt.Errorf("%w. %v accepts %d to %d operands", errOperands, op.Action, op.Arity, maxOperands)
+}
+
+// opValidation validate values of op in int64.
+// all values have to be int64, then validation rules of op are applied.
+// values are returned together with error of validation rules.
+// error is *operandError pointing at the offending value
+func opValidation(op ops.Operation, values []string) ([]int64, error) {
+	intValues := make([]int64, len(values))
+	args := make([]*big.Rat, len(values))
+	for i, v := range values {
+		n, err := stringToInt(v)
+		if err != nil {
+			return nil, &operandError{index: i, value: v, err: errType}
+		}
+		intValues[i] = n
+		args[i] = big.NewRat(n, 1)
 	}
-	return intX, intY, nil
+	return intValues, ruleValidation(op, args...)
 }
 
-// baseValidation will validate if both x and y exist
-// also they both have int type
-func baseValidation(x, y string) (int, int, error) {
-	err := qsValidation(x, y)
-	if err != nil {
-		return 0, 0, err
+// ruleValidation applies validation rules of op, like divide by zero check.
+// operands of all numeric types are converted to big.Rat.
+// the offending operand is the first one failing the rules together with operands before it
+func ruleValidation(op ops.Operation, args ...*big.Rat) error {
+	if op.Validate == nil {
+		return nil
+	}
+	err := op.Validate(args)
+	if err == nil {
+		return nil
 	}
-	intX, err := stringToInt(x)
-	if err != nil {
-		return 0, 0, errType
+	for i := range args {
+		if op.Validate(args[:i+1]) != nil {
+			return &operandError{index: i, value: args[i].RatString(), err: err}
+		}
 	}
-	intY, err := stringToInt(y)
-	if err != nil {
-		return 0, 0, errType
+	return err
+}
+
+// divModeValidation checks division mode from query string
+func divModeValidation(mode string) (string, error) {
+	if validDivMode(mode) == false {
+		return "", &fieldError{field: "mode", value: mode, err: errDivMode}
 	}
-	return intX, intY, nil
+	return mode, nil
 }
 
 // qsValidation checks if both x and y are provided
 func qsValidation(x, y string) error {
 	if x == "" {
-		return errMissX
+		return &fieldError{field: "x", err: errMissX}
 	}
 	if y == "" {
-		return errMissY
+		return &fieldError{field: "y", err: errMissY}
 	}
 	return nil
 }
 
-func stringToInt(s string) (int, error) {
-	return strconv.Atoi(s)
+// operandsValidation checks number of values of op.
+// variadic operation accepts Arity to maxOperands values, others accept Arity only
+func operandsValidation(op ops.Operation, values []string) error {
+	if len(values) < op.Arity || len(values) > maxOperands || (op.Variadic == false && len(values) != op.Arity) {
+		return &fieldError{field: "values", value: strings.Join(values, ","), err: errOperandCount(op)}
+	}
+	return nil
+}
+
+// bigOpValidation is the big.Int version of opValidation.
+// integer of any size is accepted
+func bigOpValidation(op ops.Operation, values []string) ([]*big.Int, error) {
+	bigValues := make([]*big.Int, len(values))
+	args := make([]*big.Rat, len(values))
+	for i, v := range values {
+		n, ok := new(big.Int).SetString(v, 10)
+		if ok == false {
+			return nil, &operandError{index: i, value: v, err: errBigType}
+		}
+		bigValues[i] = n
+		args[i] = new(big.Rat).SetInt(n)
+	}
+	return bigValues, ruleValidation(op, args...)
+}
+
+// decimalOpValidation is the decimal version of opValidation
+func decimalOpValidation(op ops.Operation, values []string) ([]decimal, error) {
+	decValues := make([]decimal, len(values))
+	args := make([]*big.Rat, len(values))
+	for i, v := range values {
+		d, ok := parseDecimal(v)
+		if ok == false {
+			return nil, &operandError{index: i, value: v, err: errDecimalType}
+		}
+		decValues[i] = d
+		args[i] = d.rat()
+	}
+	return decValues, ruleValidation(op, args...)
+}
+
+// rationalOpValidation is the rational version of opValidation
+func rationalOpValidation(op ops.Operation, values []string) ([]*big.Rat, error) {
+	ratValues := make([]*big.Rat, len(values))
+	for i, v := range values {
+		r, ok := parseRational(v)
+		if ok == false {
+			return nil, &operandError{index: i, value: v, err: errRationalType}
+		}
+		ratValues[i] = r
+	}
+	return ratValues, ruleValidation(op, ratValues...)
+}
+
+// scaleValidation parse scale from query string
+func scaleValidation(s string) (int, error) {
+	scale, err := strconv.Atoi(s)
+	if err != nil || validScale(scale) == false {
+		return 0, &fieldError{field: "scale", value: s, err: errScale}
+	}
+	return scale, nil
+}
+
+// roundingValidation checks rounding mode from query string
+func roundingValidation(mode string) (string, error) {
+	if validRounding(mode) == false {
+		return "", &fieldError{field: "rounding", value: mode, err: errRounding}
+	}
+	return mode, nil
+}
+
+// stringToInt parse s as 64 bit integer
+// value beyond int64 range will fail as well
+func stringToInt(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"github.com/ThisisYang/teltechcc/ops"
 	"testing"
 )
 
@@ -15,56 +18,166 @@ func TestQSValidation(t *testing.T) {
 	}
 	for _, c := range cases {
 		gotErr := qsValidation(c.x, c.y)
-		if gotErr != c.expErr {
+		if errors.Is(gotErr, c.expErr) == false {
 			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
 		}
 	}
 }
 
-func TestBaseValidation(t *testing.T) {
+func TestOpValidation(t *testing.T) {
 	cases := []struct {
-		name, x, y string
-		expX, expY int
-		expErr     error
+		name, f   string
+		values    []string
+		expValues []int64
+		expErr    error
 	}{
-		{name: "case ok", x: "3", y: "4", expX: 3, expY: 4, expErr: nil},
-		{name: "case x type err", x: "a", y: "4", expX: 0, expY: 0, expErr: errType},
-		{name: "case y type err", x: "3", y: "a", expX: 0, expY: 0, expErr: errType},
+		{name: "case ok", f: "add", values: []string{"3", "4"}, expValues: []int64{3, 4}, expErr: nil},
+		{name: "case x type err", f: "add", values: []string{"a", "4"}, expErr: errType},
+		{name: "case y type err", f: "add", values: []string{"3", "a"}, expErr: errType},
+		{name: "case float err", f: "add", values: []string{"1.5", "4"}, expErr: errType},
+		{name: "case max", f: "add", values: []string{"9223372036854775807", "-9223372036854775808"},
+			expValues: []int64{9223372036854775807, -9223372036854775808}, expErr: nil},
+		{name: "case x out of range", f: "add", values: []string{"9223372036854775808", "4"}, expErr: errType},
+		{name: "case y out of range", f: "add", values: []string{"3", "-9223372036854775809"}, expErr: errType},
+		{name: "case list", f: "add", values: []string{"1", "2", "3"}, expValues: []int64{1, 2, 3}, expErr: nil},
+		{name: "case empty value", f: "add", values: []string{"1", "", "3"}, expErr: errType},
+		{name: "case y==0", f: "div", values: []string{"3", "0"}, expValues: []int64{3, 0}, expErr: errDivideByZero},
+		{name: "case x==0", f: "div", values: []string{"0", "4"}, expValues: []int64{0, 4}, expErr: nil},
+		{name: "case last divisor==0", f: "div", values: []string{"8", "4", "0"}, expValues: []int64{8, 4, 0}, expErr: errDivideByZero},
 	}
 	for _, c := range cases {
-		gotX, gotY, gotErr := baseValidation(c.x, c.y)
-		if gotX != c.expX {
-			t.Errorf("error on: %v\ngot x:\n %v \nexp x\n %v \n", c.name, gotX, c.expX)
+		gotValues, gotErr := opValidation(getOp(c.f), c.values)
+		if fmt.Sprint(gotValues) != fmt.Sprint(c.expValues) {
+			t.Errorf("error on: %v\ngot values:\n %v \nexp values\n %v \n", c.name, gotValues, c.expValues)
 		}
-		if gotX != c.expX {
-			t.Errorf("error on: %v\ngot y:\n %v \nexp y\n %v \n", c.name, gotY, c.expY)
+		if errors.Is(gotErr, c.expErr) == false {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
 		}
+	}
+}
 
-		if gotErr != c.expErr {
+func TestOperandsValidation(t *testing.T) {
+	maxOperands = 3
+	defer func() { maxOperands = 100 }()
+	unary := ops.Operation{Name: "neg", Action: "negate", Arity: 1}
+	cases := []struct {
+		name, f string
+		// op is used if f is empty
+		op     ops.Operation
+		values []string
+		expErr bool
+	}{
+		{name: "case binary", f: "add", values: []string{"1", "2"}, expErr: false},
+		{name: "case max", f: "add", values: []string{"1", "2", "3"}, expErr: false},
+		{name: "case too many", f: "add", values: []string{"1", "2", "3", "4"}, expErr: true},
+		{name: "case too few", f: "sub", values: []string{"1"}, expErr: true},
+		{name: "case not variadic", f: "mod", values: []string{"1", "2", "3"}, expErr: true},
+		{name: "case not variadic binary", f: "mod", values: []string{"1", "2"}, expErr: false},
+		{name: "case unary", op: unary, values: []string{"1"}, expErr: false},
+		{name: "case unary with 2 operands", op: unary, values: []string{"1", "2"}, expErr: true},
+		{name: "case unary without operand", op: unary, values: []string{}, expErr: true},
+	}
+	for _, c := range cases {
+		op := c.op
+		if c.f != "" {
+			op = getOp(c.f)
+		}
+		gotErr := operandsValidation(op, c.values)
+		if (gotErr != nil) != c.expErr {
 			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
 		}
 	}
 }
 
-func TestDivValidation(t *testing.T) {
+func TestBigOpValidation(t *testing.T) {
 	cases := []struct {
-		name, x, y string
-		expX, expY int
-		expErr     error
+		name, f   string
+		values    []string
+		expValues string
+		expErr    error
 	}{
-		{name: "case y==0", x: "3", y: "0", expX: 3, expY: 0, expErr: errDivideByZero},
-		{name: "case x==0", x: "0", y: "4", expX: 0, expY: 4, expErr: nil},
+		{name: "case ok", f: "add", values: []string{"3", "4"}, expValues: "[3 4]", expErr: nil},
+		{name: "case huge", f: "add", values: []string{"123456789012345678901234567890", "-9223372036854775809"},
+			expValues: "[123456789012345678901234567890 -9223372036854775809]", expErr: nil},
+		{name: "case x type err", f: "add", values: []string{"1.5", "4"}, expErr: errBigType},
+		{name: "case y type err", f: "add", values: []string{"3", "0x10"}, expErr: errBigType},
+		{name: "case y==0", f: "div", values: []string{"123456789012345678901234567890", "0"},
+			expValues: "[123456789012345678901234567890 0]", expErr: errDivideByZero},
+		{name: "case y==-0", f: "div", values: []string{"3", "-0"}, expValues: "[3 0]", expErr: errDivideByZero},
+		{name: "case x==0", f: "div", values: []string{"0", "123456789012345678901234567890"},
+			expValues: "[0 123456789012345678901234567890]", expErr: nil},
 	}
 	for _, c := range cases {
-		gotX, gotY, gotErr := divValidation(c.x, c.y)
-		if gotX != c.expX {
-			t.Errorf("error on: %v\ngot x:\n %v \nexp x\n %v \n", c.name, gotX, c.expX)
+		gotValues, gotErr := bigOpValidation(getOp(c.f), c.values)
+		if errors.Is(gotErr, c.expErr) == false {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
 		}
-		if gotX != c.expX {
-			t.Errorf("error on: %v\ngot y:\n %v \nexp y\n %v \n", c.name, gotY, c.expY)
+		if gotValues != nil && fmt.Sprint(gotValues) != c.expValues {
+			t.Errorf("error on: %v\ngot values:\n %v \nexp values\n %v \n", c.name, gotValues, c.expValues)
 		}
+	}
+}
 
-		if gotErr != c.expErr {
+func TestDecimalOpValidation(t *testing.T) {
+	cases := []struct {
+		name, f   string
+		values    []string
+		expValues string
+		expErr    error
+	}{
+		{name: "case ok", f: "add", values: []string{"1.50", "-4"}, expValues: "[1.5 -4]", expErr: nil},
+		{name: "case x type err", f: "add", values: []string{"1,5", "4"}, expErr: errDecimalType},
+		{name: "case y type err", f: "add", values: []string{"3", "NaN"}, expErr: errDecimalType},
+		{name: "case y==0", f: "div", values: []string{"3", "0.00"}, expValues: "[3 0]", expErr: errDivideByZero},
+	}
+	for _, c := range cases {
+		gotValues, gotErr := decimalOpValidation(getOp(c.f), c.values)
+		if errors.Is(gotErr, c.expErr) == false {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+		if gotValues != nil && fmt.Sprint(gotValues) != c.expValues {
+			t.Errorf("error on: %v\ngot values:\n %v \nexp values\n %v \n", c.name, gotValues, c.expValues)
+		}
+	}
+}
+
+func TestScaleValidation(t *testing.T) {
+	cases := []struct {
+		name, s  string
+		expScale int
+		expErr   error
+	}{
+		{name: "case ok", s: "2", expScale: 2, expErr: nil},
+		{name: "case zero", s: "0", expScale: 0, expErr: nil},
+		{name: "case negative", s: "-1", expErr: errScale},
+		{name: "case too big", s: "101", expErr: errScale},
+		{name: "case type err", s: "a", expErr: errScale},
+	}
+	for _, c := range cases {
+		gotScale, gotErr := scaleValidation(c.s)
+		if gotScale != c.expScale {
+			t.Errorf("error on: %v\ngot scale:\n %v \nexp scale\n %v \n", c.name, gotScale, c.expScale)
+		}
+		if errors.Is(gotErr, c.expErr) == false {
+			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
+		}
+	}
+}
+
+func TestRationalOpValidation(t *testing.T) {
+	cases := []struct {
+		name, x, y string
+		expErr     error
+	}{
+		{name: "case ok", x: "1/3", y: "0.5", expErr: nil},
+		{name: "case empty x", x: "", y: "1/3", expErr: errRationalType},
+		{name: "case x type err", x: "1/0", y: "1/3", expErr: errRationalType},
+		{name: "case y type err", x: "1/3", y: "a/b", expErr: errRationalType},
+		{name: "case y==0", x: "1/3", y: "0/5", expErr: errDivideByZero},
+	}
+	for _, c := range cases {
+		_, gotErr := rationalOpValidation(getOp("div"), []string{c.x, c.y})
+		if errors.Is(gotErr, c.expErr) == false {
 			t.Errorf("error on: %v\ngot err:\n %v \nexp err\n %v \n", c.name, gotErr, c.expErr)
 		}
 	}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// API versions, unversioned routes are aliases of v1
+const (
+	v1 = "v1"
+	v2 = "v2"
+)
+
+// versionKey is the key of API version in gin.Context
+const versionKey = "version"
+
+// apiVersion is the middleware of route group of version v.
+// v1 is deprecated, its responses have Deprecation header and
+// Link header pointing at the same route of v2
+func apiVersion(v string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(versionKey, v)
+		if v == v1 {
+			path := strings.TrimPrefix(ctx.Request.URL.Path, "/"+v1)
+			ctx.Header("Deprecation", "true")
+			ctx.Header("Link", fmt.Sprintf("</%v%v>; rel=\"successor-version\"", v2, path))
+		}
+		ctx.Next()
+	}
+}
+
+// versioned convert response of handlers, which is v1, to the version of request.
+// gin.H and []gin.H are converted, others are returned as is
+func versioned(ctx *gin.Context, data interface{}) interface{} {
+	if ctx.GetString(versionKey) != v2 {
+		return data
+	}
+	switch data := data.(type) {
+	case gin.H:
+		return v2Response(data)
+	case []gin.H:
+		results := make([]gin.H, len(data))
+		for i, h := range data {
+			results[i] = v2Response(h)
+		}
+		return results
+	}
+	return data
+}
+
+// v2Response convert v1 response h to v2:
+// operands, answer and remainder are always strings, so that int64 and big integer
+// share the same type and JSON decoder of client won't lose precision.
+// calculation results have op, the name of operation same as batch,
+// and type, integer, decimal or rational. integer has precision as well.
+// problem details and other responses without action are not changed
+func v2Response(h gin.H) gin.H {
+	if _, ok := h["action"]; ok == false {
+		return h
+	}
+	resp := make(gin.H, len(h)+3)
+	for k, v := range h {
+		switch k {
+		case "x", "y", "answer", "remainder":
+			resp[k] = fmt.Sprint(v)
+		case "values":
+			values := v.([]interface{})
+			strValues := make([]interface{}, len(values))
+			for i, value := range values {
+				strValues[i] = fmt.Sprint(value)
+			}
+			resp[k] = strValues
+		default:
+			resp[k] = v
+		}
+	}
+	if op, ok := findOp(fmt.Sprint(h["action"])); ok {
+		resp["op"] = op.Name
+	}
+	if _, ok := resp["type"]; ok == false {
+		resp["type"] = typeInteger
+	}
+	if _, ok := resp["precision"]; ok == false && resp["type"] == typeInteger {
+		resp["precision"] = precisionInt
+	}
+	return resp
+}
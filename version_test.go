@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"testing"
+)
+
+func TestV1Alias(t *testing.T) {
+	setUpLogger(false)
+	urls := []string{
+		"/add?x=1&y=3", "/divide?x=-7&y=2", "/multiply?values=2,3,4", "/add?x=1&y=3&precision=big",
+		"/divide?x=1&y=3&type=decimal", "/rational/add?x=1/2&y=1/3", "/eval?expr=1%2B2", "/add?x=1", "/health",
+	}
+	router := newRouter()
+	for _, url := range urls {
+		cache = NewFakeCache()
+		exp := performRequest(router, "GET", url)
+		cache = NewFakeCache()
+		got := performRequest(router, "GET", "/v1"+url)
+		if got.Code != exp.Code {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", url, got.Code, exp.Code)
+		}
+		gotBody, expBody := got.Body.String(), exp.Body.String()
+		if got.Code >= 400 {
+			// instance of problem details is the request URI, the only difference
+			expBody = strings.Replace(expBody, `"instance":"`, `"instance":"/v1`, 1)
+		}
+		if gotBody != expBody {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", url, gotBody, expBody)
+		}
+		if got.Header().Get("Deprecation") != "true" || exp.Header().Get("Deprecation") != "true" {
+			t.Errorf("error on: %v\nDeprecation header is not set\n", url)
+		}
+	}
+}
+
+func TestDeprecation(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url               string
+		expDeprecation, expLink string
+	}{
+		{name: "case unversioned", url: "/add?x=1&y=2", expDeprecation: "true", expLink: `</v2/add>; rel="successor-version"`},
+		{name: "case v1", url: "/v1/rational/add?x=1&y=2", expDeprecation: "true", expLink: `</v2/rational/add>; rel="successor-version"`},
+		{name: "case v2", url: "/v2/add?x=1&y=2", expDeprecation: "", expLink: ""},
+	}
+	router := newRouter()
+	for _, c := range cases {
+		cache = NewFakeCache()
+		w := performRequest(router, "GET", c.url)
+		if got := w.Header().Get("Deprecation"); got != c.expDeprecation {
+			t.Errorf("error on: %v\ngot deprecation:\n %v \nexp deprecation\n %v \n", c.name, got, c.expDeprecation)
+		}
+		if got := w.Header().Get("Link"); got != c.expLink {
+			t.Errorf("error on: %v\ngot link:\n %v \nexp link\n %v \n", c.name, got, c.expLink)
+		}
+	}
+}
+
+func TestV2(t *testing.T) {
+	setUpLogger(false)
+	cases := []struct {
+		name, url     string
+		expStatusCode int
+		expBody       interface{}
+		fCache        *fakeCacheClient
+	}{
+		{
+			name: "case uncached", url: "/v2/add?x=1&y=3", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "op": "add", "x": "1", "y": "3", "answer": "4", "cached": false, "type": "integer", "precision": "int"},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case cached", url: "/v2/subtract?x=3&y=1", expStatusCode: 200,
+			expBody: gin.H{"action": "subtract", "op": "sub", "x": "3", "y": "1", "answer": "2", "cached": true, "type": "integer", "precision": "int"},
+			fCache:  &fakeCacheClient{val: map[string]string{"sub:3:1": "2"}},
+		},
+		{
+			name: "case values", url: "/v2/multiply?values=2,3,4", expStatusCode: 200,
+			expBody: gin.H{"action": "multiply", "op": "mul", "values": []string{"2", "3", "4"}, "answer": "24", "cached": false, "type": "integer", "precision": "int"},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case remainder", url: "/v2/divide?x=-7&y=2", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "op": "div", "x": "-7", "y": "2", "answer": "-4", "remainder": "1", "mode": "floor", "cached": false,
+				"type": "integer", "precision": "int"},
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case big", url: "/v2/add?x=9223372036854775807&y=1&precision=big", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "op": "add", "x": "9223372036854775807", "y": "1", "answer": "9223372036854775808", "cached": false,
+				"type": "integer", "precision": "big"},
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case decimal", url: "/v2/divide?x=1&y=3&type=decimal&scale=2", expStatusCode: 200,
+			expBody: gin.H{"action": "divide", "op": "div", "x": "1", "y": "3", "answer": "0.33", "cached": false,
+				"type": "decimal", "scale": 2, "rounding": "half-even"},
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case rational", url: "/v2/rational/add?x=1/2&y=1/3", expStatusCode: 200,
+			expBody: gin.H{"action": "add", "op": "add", "x": "1/2", "y": "1/3", "answer": "5/6", "cached": false,
+				"type": "rational", "numerator": "5", "denominator": "6"},
+			fCache: NewFakeCache(),
+		},
+		{
+			name: "case eval", url: "/v2/eval?expr=2*3", expStatusCode: 200,
+			expBody: gin.H{"action": "eval", "expr": "2*3", "answer": "6", "nodes": 1, "hit": 0, "type": "integer", "precision": "int"},
+			fCache:  NewFakeCache(),
+		},
+		{
+			name: "case problem", url: "/v2/add?x=1", expStatusCode: 400,
+			expBody: problemBody("/v2/add?x=1", codeMissingOperand, errMissY, "y", ""), fCache: NewFakeCache(),
+		},
+		{
+			name: "case health", url: "/v2/health", expStatusCode: 200,
+			expBody: gin.H{"cache": "OK", "hit": 0, "size": 0}, fCache: NewFakeCache(),
+		},
+	}
+
+	router := newRouter()
+	for _, c := range cases {
+		cache = c.fCache
+		w := performRequest(router, "GET", c.url)
+		jsonEncoded, _ := json.Marshal(c.expBody)
+
+		if w.Code != c.expStatusCode {
+			t.Errorf("error on: %v\ngot code:\n %v \nexp code\n %v \n", c.name, w.Code, c.expStatusCode)
+		}
+		if w.Body.String() != string(jsonEncoded) {
+			t.Errorf("error on: %v\ngot body:\n %v \nexp body\n %v \n", c.name, w.Body.String(), string(jsonEncoded))
+		}
+	}
+
+	// batch items are converted one by one
+	cache = NewFakeCache()
+	w := performRequestWithBody(router, "POST", "/v2/batch", "application/json", `[{"op": "add", "x": 1, "y": 3}, {"op": "add", "x": 1}]`)
+	jsonEncoded, _ := json.Marshal([]gin.H{
+		{"action": "add", "op": "add", "x": "1", "y": "3", "answer": "4", "cached": false, "type": "integer", "precision": "int"},
+		problemBody("", codeMissingOperand, errMissY, "y", ""),
+	})
+	if w.Body.String() != string(jsonEncoded) {
+		t.Errorf("error on: batch\ngot body:\n %v \nexp body\n %v \n", w.Body.String(), string(jsonEncoded))
+	}
+}
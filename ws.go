@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// wsGUID is used to compute Sec-WebSocket-Accept, see RFC 6455 section 1.3
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// opcodes of WebSocket frames
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// status codes of close frames
+const (
+	wsCloseNormal      = 1000
+	wsCloseGoingAway   = 1001
+	wsCloseProtocol    = 1002
+	wsCloseUnsupported = 1003
+	wsCloseTooBig      = 1009
+)
+
+// maxMessageSize is the max size of a message from client, fragments included
+const maxMessageSize = 4096
+
+// wsIdleTimeout close connection without message for a while
+var wsIdleTimeout = 5 * time.Minute
+
+// wsWriteTimeout is the write deadline of a frame.
+// close frames on shutdown have wsCloseTimeout instead, so that a slow client does not hold the shutdown
+var (
+	wsWriteTimeout = 10 * time.Second
+	wsCloseTimeout = time.Second
+)
+
+// wsRate is the number of messages a connection is allowed per second,
+// up to wsBurst messages at once. messages over the limit get rate_limited error
+var (
+	wsRate  = 20.0
+	wsBurst = 20.0
+)
+
+var errWSHandshake = fmt.Errorf("Not a WebSocket handshake. GET with Upgrade: websocket, Connection: Upgrade, Sec-WebSocket-Key and Sec-WebSocket-Version: 13 only")
+var errWSMessage = fmt.Errorf("Invalid message. JSON object of id, op and params only")
+var errRateLimited = fmt.Errorf("Too many messages. Slow down")
+
+// wsCloseError close connection with code
+type wsCloseError struct {
+	code   int
+	reason string
+}
+
+func (e *wsCloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %v", e.code, e.reason)
+}
+
+// wsRequest is a message from client.
+// op and params are the same as method and params of JSON-RPC, id is echoed in reply
+type wsRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Op     string          `json:"op"`
+	Params json.RawMessage `json:"params"`
+}
+
+// websockets are open connections, they are closed on shutdown of the HTTP server, see newServer
+var websockets = &wsConns{conns: make(map[*wsConn]struct{})}
+
+type wsConns struct {
+	mutex sync.Mutex
+	conns map[*wsConn]struct{}
+}
+
+func (h *wsConns) add(c *wsConn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.conns[c] = struct{}{}
+}
+
+func (h *wsConns) remove(c *wsConn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.conns, c)
+}
+
+// closeAll send going away close frame to every connection and close them.
+// the connections are copied so that the lock is not held by writes,
+// which share a deadline of wsCloseTimeout
+func (h *wsConns) closeAll() {
+	h.mutex.Lock()
+	conns := make([]*wsConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mutex.Unlock()
+
+	deadline := time.Now().Add(wsCloseTimeout)
+	for _, c := range conns {
+		// pending write of a reply is cut short by the deadline as well
+		c.conn.SetWriteDeadline(deadline)
+		c.writeFrameBefore(wsClose, wsClosePayload(wsCloseGoingAway, "Server shutting down"), deadline)
+		c.conn.Close()
+	}
+}
+
+// wsConn is a server side WebSocket connection, writes are safe for concurrent use
+type wsConn struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	mutex sync.Mutex
+}
+
+// ws endpoint, calculation session over WebSocket.
+// every text message is a request of {id, op, params}, replied with
+// {id, type: result, result} or {id, type: error, error}.
+// result is the same as response of endpoints, error is problem details
+func ws(ctx *gin.Context) {
+	key, err := wsHandshake(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	conn, rw, err := ctx.Writer.Hijack()
+	if err != nil {
+		Error.Println("websocket hijack failure:", err)
+		return
+	}
+	fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %v\r\n%v: %v\r\n\r\n",
+		wsAccept(key), requestIDHeader, ctx.GetString(requestIDKey))
+
+	c := &wsConn{conn: conn, r: rw.Reader}
+	websockets.add(c)
+	defer websockets.remove(c)
+	defer conn.Close()
+	c.serve(ctx.GetString(requestIDKey))
+}
+
+// wsHandshake checks the opening handshake, Sec-WebSocket-Key is returned
+func wsHandshake(ctx *gin.Context) (string, error) {
+	headers := []struct{ name, token string }{
+		{"Upgrade", "websocket"},
+		{"Connection", "upgrade"},
+		{"Sec-WebSocket-Version", "13"},
+	}
+	for _, h := range headers {
+		if headerToken(ctx.GetHeader(h.name), h.token) == false {
+			return "", &fieldError{field: h.name, value: ctx.GetHeader(h.name), err: errWSHandshake}
+		}
+	}
+	key := ctx.GetHeader("Sec-WebSocket-Key")
+	if key == "" {
+		return "", &fieldError{field: "Sec-WebSocket-Key", err: errWSHandshake}
+	}
+	return key, nil
+}
+
+// headerToken checks if comma separated header contains token, case insensitive
+func headerToken(header, token string) bool {
+	for _, t := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// wsAccept return Sec-WebSocket-Accept of key
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// serve reply messages until connection is closed.
+// requestID of the handshake is returned in problem details
+func (c *wsConn) serve(requestID string) {
+	limiter := newRateLimiter(wsRate, wsBurst)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(wsIdleTimeout))
+		msg, err := c.readMessage()
+		if err != nil {
+			if e, ok := err.(*wsCloseError); ok {
+				c.writeClose(e.code, e.reason)
+			} else if err != io.EOF {
+				Debug.Println("websocket read err:", err)
+			}
+			return
+		}
+		var reply gin.H
+		if limiter.allow(time.Now()) {
+			reply = handleWSMessage(msg, requestID)
+		} else {
+			reply = wsReply(wsMessageID(msg), nil, problemOf(errRateLimited, codeRateLimited), requestID)
+		}
+		b, _ := json.Marshal(reply)
+		if c.writeFrame(wsText, b) != nil {
+			return
+		}
+	}
+}
+
+// handleWSMessage calculate request in msg, through the same path as JSON-RPC
+func handleWSMessage(msg []byte, requestID string) gin.H {
+	var req wsRequest
+	err := json.Unmarshal(msg, &req)
+	if err != nil || req.Op == "" || validRPCID(req.ID) == false {
+		return wsReply(wsMessageID(msg), nil, problemOf(errWSMessage, codeInvalidBody), requestID)
+	}
+	Debug.Println("recieved websocket:", req.Op, string(req.Params))
+	result, rpcErr := callRPC(req.Op, req.Params, opTTL)
+	if rpcErr != nil {
+		fallback := codeInternal
+		if rpcErr.code == rpcCalcError {
+			fallback = codeCalculation
+		}
+		return wsReply(req.ID, nil, problemOf(rpcErr.err, fallback), requestID)
+	}
+	return wsReply(req.ID, result, nil, requestID)
+}
+
+// wsMessageID return id of msg if any, so that invalid message can be matched by client
+func wsMessageID(msg []byte) json.RawMessage {
+	var req struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(msg, &req) != nil || validRPCID(req.ID) == false {
+		return nil
+	}
+	return req.ID
+}
+
+// wsReply return reply of result or p, id is null if it is unknown
+func wsReply(id json.RawMessage, result gin.H, p *problem, requestID string) gin.H {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if p != nil {
+		return gin.H{"id": id, "type": "error", "error": p.body("", requestID)}
+	}
+	return gin.H{"id": id, "type": "result", "result": result}
+}
+
+// readMessage return payload of the next text message.
+// fragments are joined, ping is replied with pong.
+// io.EOF is returned once close frame is received and replied
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	fragmented := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case wsPing:
+			if c.writeFrame(wsPong, payload) != nil {
+				return nil, io.EOF
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			code := wsCloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.writeClose(code, "")
+			return nil, io.EOF
+		case wsText:
+			if fragmented {
+				return nil, &wsCloseError{wsCloseProtocol, "Expect continuation frame"}
+			}
+		case wsContinuation:
+			if fragmented == false {
+				return nil, &wsCloseError{wsCloseProtocol, "Unexpected continuation frame"}
+			}
+		case wsBinary:
+			return nil, &wsCloseError{wsCloseUnsupported, "Text messages only"}
+		default:
+			return nil, &wsCloseError{wsCloseProtocol, "Unknown opcode"}
+		}
+		if len(msg)+len(payload) > maxMessageSize {
+			return nil, &wsCloseError{wsCloseTooBig, fmt.Sprintf("Message is too big, max %d bytes", maxMessageSize)}
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+		fragmented = true
+	}
+}
+
+// readFrame read a frame from client, payload is unmasked
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	_, err := io.ReadFull(c.r, header[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode := header[0]&0x80 != 0, header[0]&0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, &wsCloseError{wsCloseProtocol, "Reserved bits are set"}
+	}
+	// frames from client must be masked
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, &wsCloseError{wsCloseProtocol, "Frame is not masked"}
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.r, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.r, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if opcode >= wsClose && (length > 125 || fin == false) {
+		return false, 0, nil, &wsCloseError{wsCloseProtocol, "Invalid control frame"}
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, &wsCloseError{wsCloseTooBig, fmt.Sprintf("Message is too big, max %d bytes", maxMessageSize)}
+	}
+	var mask [4]byte
+	_, err = io.ReadFull(c.r, mask[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.r, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeFrame write an unfragmented and unmasked frame in wsWriteTimeout
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	return c.writeFrameBefore(opcode, payload, time.Now().Add(wsWriteTimeout))
+}
+
+// writeFrameBefore write an unfragmented and unmasked frame before deadline
+func (c *wsConn) writeFrameBefore(opcode byte, payload []byte, deadline time.Time) error {
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conn.SetWriteDeadline(deadline)
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// writeClose write close frame of code and reason
+func (c *wsConn) writeClose(code int, reason string) error {
+	return c.writeFrame(wsClose, wsClosePayload(code, reason))
+}
+
+// wsClosePayload return payload of close frame of code and reason
+func wsClosePayload(code int, reason string) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(payload, reason...)
+}
+
+// rateLimiter is a token bucket, tokens are refilled at rate per second up to burst
+type rateLimiter struct {
+	rate, burst, tokens float64
+	last                time.Time
+}
+
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// allow take a token at now, false if there is none
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
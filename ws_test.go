@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsDial open WebSocket connection to addr, handshake is checked
+func wsDial(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: %v\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %v\r\nSec-WebSocket-Version: 13\r\n\r\n", addr, key)
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 101 || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("error on: handshake\ngot status and accept:\n %v %v \n", resp.StatusCode, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+	return conn, r
+}
+
+// wsSend write a masked frame, like browser does
+func wsSend(conn net.Conn, fin bool, opcode byte, payload []byte) {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	if len(payload) <= 125 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	conn.Write(frame)
+}
+
+// wsReceive read an unfragmented frame from server
+func wsReceive(t *testing.T, conn net.Conn, r *bufio.Reader) (byte, []byte) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var header [2]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	io.ReadFull(r, payload)
+	return header[0] & 0x0F, payload
+}
+
+func TestWSAccept(t *testing.T) {
+	// example of RFC 6455 section 1.3
+	got := wsAccept("dGhlIHNhbXBsZSBub25jZQ==")
+	exp := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+	if got != exp {
+		t.Errorf("error on: case accept\ngot accept:\n %v \nexp accept\n %v \n", got, exp)
+	}
+}
+
+func TestWSHandshake(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	router := newRouter()
+	w := performRequestWithHeader(router, "GET", "/ws", "Upgrade", "h2c")
+	exp, _ := json.Marshal(problemBody("/ws", codeInvalidParameter, errWSHandshake, "Upgrade", "h2c"))
+	if w.Code != 400 || w.Body.String() != string(exp) {
+		t.Errorf("error on: case handshake\ngot status and body:\n %v %v \nexp status and body\n %v %v \n", w.Code, w.Body.String(), 400, string(exp))
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	cache = NewFakeCache()
+	ts := httptest.NewServer(newRouter())
+	defer ts.Close()
+	conn, r := wsDial(t, ts.Listener.Addr().String())
+	defer conn.Close()
+
+	cases := []struct {
+		name, msg string
+		exp       gin.H
+	}{
+		{
+			name: "case result", msg: `{"id": 1, "op": "add", "params": {"x": 2, "y": 5}}`,
+			exp: gin.H{"id": 1, "type": "result", "result": gin.H{"action": "add", "x": 2, "y": 5, "answer": 7, "cached": false}},
+		},
+		{
+			name: "case cached", msg: `{"id": "b", "op": "add", "params": [5, 2]}`,
+			exp: gin.H{"id": "b", "type": "result", "result": gin.H{"action": "add", "x": 5, "y": 2, "answer": 7, "cached": true}},
+		},
+		{
+			name: "case invalid type", msg: `{"id": 2, "op": "multiply", "params": {"x": "a", "y": 5}}`,
+			exp: gin.H{"id": 2, "type": "error", "error": problemBody("", codeInvalidType, errType, "x", "a")},
+		},
+		{
+			name: "case unknown op", msg: `{"id": 3, "op": "pow", "params": [2, 5]}`,
+			exp: gin.H{"id": 3, "type": "error", "error": problemBody("", codeUnsupported, errRPCMethod("pow"), "", "")},
+		},
+		{
+			name: "case invalid message", msg: `{"id": 4, "params": [2, 5]}`,
+			exp: gin.H{"id": 4, "type": "error", "error": problemBody("", codeInvalidBody, errWSMessage, "", "")},
+		},
+		{
+			name: "case invalid JSON", msg: `add 2 5`,
+			exp: gin.H{"id": nil, "type": "error", "error": problemBody("", codeInvalidBody, errWSMessage, "", "")},
+		},
+	}
+	for _, c := range cases {
+		wsSend(conn, true, wsText, []byte(c.msg))
+		opcode, payload := wsReceive(t, conn, r)
+		exp, _ := json.Marshal(c.exp)
+		if opcode != wsText || string(payload) != string(exp) {
+			t.Errorf("error on: %v\ngot reply:\n %v %v \nexp reply\n %v %v \n", c.name, opcode, string(payload), wsText, string(exp))
+		}
+	}
+
+	// ping is replied in between fragments of a message
+	wsSend(conn, false, wsText, []byte(`{"id": 5, "op": "sub`))
+	wsSend(conn, true, wsPing, []byte("hi"))
+	wsSend(conn, true, wsContinuation, []byte(`tract", "params": [9, 4]}`))
+	opcode, payload := wsReceive(t, conn, r)
+	if opcode != wsPong || string(payload) != "hi" {
+		t.Errorf("error on: case ping\ngot frame:\n %v %v \nexp frame\n %v %v \n", opcode, string(payload), wsPong, "hi")
+	}
+	opcode, payload = wsReceive(t, conn, r)
+	exp, _ := json.Marshal(gin.H{"id": 5, "type": "result", "result": gin.H{"action": "subtract", "x": 9, "y": 4, "answer": 5, "cached": false}})
+	if opcode != wsText || string(payload) != string(exp) {
+		t.Errorf("error on: case fragments\ngot reply:\n %v %v \nexp reply\n %v %v \n", opcode, string(payload), wsText, string(exp))
+	}
+
+	wsSend(conn, true, wsClose, binary.BigEndian.AppendUint16(nil, wsCloseNormal))
+	opcode, payload = wsReceive(t, conn, r)
+	if opcode != wsClose || binary.BigEndian.Uint16(payload) != wsCloseNormal {
+		t.Errorf("error on: case close\ngot frame:\n %v %v \nexp frame\n %v %v \n", opcode, payload, wsClose, wsCloseNormal)
+	}
+}
+
+func TestWebSocketRateLimit(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	cache = NewFakeCache()
+	rate, burst := wsRate, wsBurst
+	wsRate, wsBurst = 0.001, 2
+	defer func() { wsRate, wsBurst = rate, burst }()
+	ts := httptest.NewServer(newRouter())
+	defer ts.Close()
+	conn, r := wsDial(t, ts.Listener.Addr().String())
+	defer conn.Close()
+
+	for i := 0; i < 3; i++ {
+		wsSend(conn, true, wsText, []byte(fmt.Sprintf(`{"id": %d, "op": "health"}`, i)))
+	}
+	exp := []gin.H{
+		{"id": 0, "type": "result", "result": gin.H{"cache": "OK", "hit": 0, "size": 0}},
+		{"id": 1, "type": "result", "result": gin.H{"cache": "OK", "hit": 0, "size": 0}},
+		{"id": 2, "type": "error", "error": problemBody("", codeRateLimited, errRateLimited, "", "")},
+	}
+	for i := range exp {
+		_, payload := wsReceive(t, conn, r)
+		e, _ := json.Marshal(exp[i])
+		if string(payload) != string(e) {
+			t.Errorf("error on: message %d\ngot reply:\n %v \nexp reply\n %v \n", i, string(payload), string(e))
+		}
+	}
+}
+
+func TestWebSocketProtocolError(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	ts := httptest.NewServer(newRouter())
+	defer ts.Close()
+	cases := []struct {
+		name    string
+		send    func(conn net.Conn)
+		expCode uint16
+	}{
+		{name: "case binary", send: func(conn net.Conn) { wsSend(conn, true, wsBinary, []byte{1}) }, expCode: wsCloseUnsupported},
+		{name: "case unmasked", send: func(conn net.Conn) { conn.Write([]byte{0x81, 0x01, 'a'}) }, expCode: wsCloseProtocol},
+		{name: "case too big", send: func(conn net.Conn) {
+			wsSend(conn, true, wsText, []byte(strings.Repeat("a", maxMessageSize+1)))
+		}, expCode: wsCloseTooBig},
+	}
+	for _, c := range cases {
+		conn, r := wsDial(t, ts.Listener.Addr().String())
+		c.send(conn)
+		opcode, payload := wsReceive(t, conn, r)
+		if opcode != wsClose || binary.BigEndian.Uint16(payload) != c.expCode {
+			t.Errorf("error on: %v\ngot frame:\n %v %v \nexp close code\n %v \n", c.name, opcode, payload, c.expCode)
+		}
+		conn.Close()
+	}
+}
+
+func TestWebSocketShutdown(t *testing.T) {
+	setUpLogger(false)
+	gin.SetMode(gin.TestMode)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newServer("127.0.0.1", 0)
+	go s.Serve(l)
+	conn, r := wsDial(t, l.Addr().String())
+	defer conn.Close()
+
+	s.Shutdown(context.Background())
+	opcode, payload := wsReceive(t, conn, r)
+	if opcode != wsClose || binary.BigEndian.Uint16(payload) != wsCloseGoingAway {
+		t.Errorf("error on: case shutdown\ngot frame:\n %v %v \nexp close code\n %v \n", opcode, payload, wsCloseGoingAway)
+	}
+}
+
+func TestWebSocketCloseAll(t *testing.T) {
+	defer func(d time.Duration) { wsCloseTimeout = d }(wsCloseTimeout)
+	wsCloseTimeout = 100 * time.Millisecond
+	h := &wsConns{conns: make(map[*wsConn]struct{})}
+	// clients never read, writes to net.Pipe block until the deadline
+	for i := 0; i < 3; i++ {
+		server, client := net.Pipe()
+		defer client.Close()
+		h.add(&wsConn{conn: server})
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		h.closeAll()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("error on: case close all\ncloseAll is blocked")
+	}
+	// writes share a deadline rather than wsCloseTimeout each
+	if elapsed := time.Since(start); elapsed > 2*wsCloseTimeout {
+		t.Errorf("error on: case close all\ngot elapsed:\n %v \nexp elapsed less than\n %v \n", elapsed, 2*wsCloseTimeout)
+	}
+}